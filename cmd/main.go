@@ -1,9 +1,74 @@
 package main
 
 import (
+	"flag"
+	"fmt"
+	"os"
+
 	bq "github.com/GoogleCloudPlatform/protoc-gen-bq-schema/pkg"
+	"github.com/golang/glog"
+	"google.golang.org/protobuf/proto"
+)
+
+var (
+	descriptorSetIn = flag.String("descriptor_set_in", "", "generate from the given FileDescriptorSets, separated like "+
+		"protoc's --descriptor_set_in, rather than running as a protoc plugin")
+	out = flag.String("out", ".", "directory to write the generated files to, with --descriptor_set_in")
+	opt = flag.String("opt", "", "parameters, as given to protoc with --bq-schema_opt, with --descriptor_set_in")
 )
 
 func main() {
-	bq.Do()
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s --descriptor_set_in=<file> [--out=<dir>] [--opt=<parameters>] [<file or message>...]\n\n", os.Args[0])
+		fmt.Fprintln(flag.CommandLine.Output(), "Without --descriptor_set_in, runs as a protoc plugin.")
+		flag.PrintDefaults()
+		fmt.Fprintln(flag.CommandLine.Output(), "\nParameters, given with --opt or with protoc's --bq-schema_opt, separated by commas:")
+		bq.PrintParameters(flag.CommandLine.Output())
+	}
+	flag.Parse()
+
+	if *descriptorSetIn != "" {
+		if err := generateFromDescriptorSet(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	req, res := bq.GetCodeGenRequestResponse(os.Stdin)
+	if res.Error == nil {
+		var err error
+		if res, err = bq.NewGenerator().Generate(req); err != nil {
+			glog.Exitf("cannot generate schemas: %v", err)
+		}
+	}
+
+	data, err := proto.Marshal(res)
+	if err != nil {
+		glog.Exitf("cannot marshal response: %v", err)
+	}
+	if _, err = os.Stdout.Write(data); err != nil {
+		glog.Exitf("failed to write response: %v", err)
+	}
+}
+
+// generateFromDescriptorSet generates the files or messages given as arguments from the descriptor sets
+// given with --descriptor_set_in, and writes the outputs under --out.
+func generateFromDescriptorSet() error {
+	set, err := bq.ReadDescriptorSets(*descriptorSetIn)
+	if err != nil {
+		return err
+	}
+	req, messages, err := bq.NewRequest(set, *opt, flag.Args())
+	if err != nil {
+		return err
+	}
+	res, err := bq.NewGenerator(bq.WithMessages(messages...)).Generate(req)
+	if err != nil {
+		return err
+	}
+	if res.Error != nil {
+		return fmt.Errorf("%s", res.GetError())
+	}
+	return bq.WriteFiles(*out, res.GetFile())
 }
@@ -6,6 +6,7 @@ require (
 	github.com/golang/glog v1.0.0
 	github.com/golang/protobuf v1.5.2
 	google.golang.org/protobuf v1.28.0
+	sigs.k8s.io/yaml v1.3.0
 )
 
 replace github.com/GoogleCloudPlatform/protoc-gen-bq-schema => github.com/Unity-Technologies/protoc-gen-bq-schema v0.0.0-20220527214952-71b6ec77d247
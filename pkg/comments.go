@@ -11,6 +11,7 @@ const (
 	messagePath    = 4 // FileDescriptorProto.message_type
 	fieldPath      = 2 // DescriptorProto.field
 	subMessagePath = 3 // DescriptorProto.nested_type
+	oneofPath      = 8 // DescriptorProto.oneof_decl
 )
 
 // Comments is a map between path in FileDescriptorProto and leading/trailing comments for each field.
@@ -0,0 +1,94 @@
+package pkg
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// typeWidenings lists, for each column type, the types it can be changed to in-place.
+var typeWidenings = map[string]map[string]bool{
+	"INT64":   {"NUMERIC": true, "BIGNUMERIC": true, "FLOAT64": true},
+	"NUMERIC": {"BIGNUMERIC": true, "FLOAT64": true},
+}
+
+// checkCompatibility compares the schema of a table with the one previously generated for it at the given
+// path in the directory given with the previous-schemas parameter, laid out like the output directory. It returns an
+// error listing the changes which BigQuery rejects when updating the schema of an existing table, and
+// otherwise keeps the previous schema in table.Previous. Tables without a previous schema are new, and
+// always compatible.
+func (g *generation) checkCompatibility(schemaPath string, table *Table) error {
+	root := g.params.PreviousSchemas
+	if root == "" {
+		return nil
+	}
+	path := filepath.Join(root, filepath.FromSlash(schemaPath))
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	var previous Schema
+	if err = json.Unmarshal(data, &previous); err != nil {
+		return fmt.Errorf("cannot parse previous schema %s: %v", path, err)
+	}
+	if changes := incompatibleChanges("", previous, table.Schema); len(changes) > 0 {
+		return fmt.Errorf("schema of table %s has changes incompatible with %s:\n  %s", table.Name, path, strings.Join(changes, "\n  "))
+	}
+	table.Previous = previous
+	return nil
+}
+
+// incompatibleChanges describes the changes from the previous to the current schema which BigQuery
+// rejects in-place, prefixing the names of columns with prefix.
+func incompatibleChanges(prefix string, previous, current Schema) []string {
+	var changes []string
+
+	columns := make(map[string]*Field, len(current))
+	for _, f := range current {
+		columns[strings.ToLower(f.Name)] = f
+	}
+	for _, prev := range previous {
+		name := prefix + prev.Name
+		cur, ok := columns[strings.ToLower(prev.Name)]
+		if !ok {
+			changes = append(changes, fmt.Sprintf("column %s was removed", name))
+			continue
+		}
+		delete(columns, strings.ToLower(prev.Name))
+
+		prevType, curType := sqlType(prev), sqlType(cur)
+		if prevType != curType && !typeWidenings[prevType][curType] {
+			changes = append(changes, fmt.Sprintf("column %s changed type from %s to %s", name, prev.Type, cur.Type))
+		}
+		prevMode, curMode := fieldMode(prev), fieldMode(cur)
+		switch {
+		case prevMode == curMode:
+		case prevMode == "REPEATED" || curMode == "REPEATED":
+			changes = append(changes, fmt.Sprintf("column %s changed mode from %s to %s", name, prevMode, curMode))
+		case curMode == "REQUIRED":
+			changes = append(changes, fmt.Sprintf("column %s changed mode from %s to REQUIRED", name, prevMode))
+		}
+		if prevType == "STRUCT" && curType == "STRUCT" {
+			changes = append(changes, incompatibleChanges(name+".", prev.Fields, cur.Fields)...)
+		}
+	}
+	for _, f := range current {
+		if _, added := columns[strings.ToLower(f.Name)]; added && fieldMode(f) == "REQUIRED" {
+			changes = append(changes, fmt.Sprintf("column %s was added as REQUIRED", prefix+f.Name))
+		}
+	}
+	return changes
+}
+
+// fieldMode returns the mode of a column, which defaults to NULLABLE in JSON schemas.
+func fieldMode(f *Field) string {
+	if f.Mode == "" {
+		return "NULLABLE"
+	}
+	return f.Mode
+}
@@ -0,0 +1,167 @@
+package pkg
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sqlTypes maps the legacy type names used in JSON schemas to their GoogleSQL names.
+var sqlTypes = map[string]string{
+	"INTEGER": "INT64",
+	"FLOAT":   "FLOAT64",
+	"BOOLEAN": "BOOL",
+	"RECORD":  "STRUCT",
+}
+
+// renderDDL renders a table as a BigQuery CREATE TABLE statement.
+func renderDDL(table *Table) (string, error) {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "CREATE TABLE IF NOT EXISTS %s (\n", quoteIdentifier(table.QualifiedName()))
+	writeColumns(&b, table.Schema, "  ")
+	b.WriteString(")")
+	if partitioning := partitionExpression(table); partitioning != "" {
+		b.WriteString("\nPARTITION BY " + partitioning)
+	}
+	if fields := table.Options.GetClusteringFields(); len(fields) > 0 {
+		columns := make([]string, len(fields))
+		for idx, name := range fields {
+			columns[idx] = quoteIdentifier(name)
+		}
+		b.WriteString("\nCLUSTER BY " + strings.Join(columns, ", "))
+	}
+	if options := tableOptions(table); len(options) > 0 {
+		b.WriteString("\nOPTIONS(\n  ")
+		b.WriteString(strings.Join(options, ",\n  "))
+		b.WriteString("\n)")
+	}
+	b.WriteString(";\n")
+	return b.String(), nil
+}
+
+// tableOptions returns the entries of the OPTIONS clause of a table.
+func tableOptions(table *Table) []string {
+	var options []string
+	if table.Description != "" {
+		options = append(options, "description="+quoteString(table.Description))
+	}
+	if ms := table.Options.GetTimePartitioning().GetExpirationMs(); ms > 0 {
+		options = append(options, "partition_expiration_days="+strconv.FormatFloat(float64(ms)/msPerDay, 'f', -1, 64))
+	}
+	if table.Options.GetRequirePartitionFilter() {
+		options = append(options, "require_partition_filter=true")
+	}
+	if ms := table.Options.GetExpirationTime(); ms != 0 {
+		expiration := time.Unix(0, ms*int64(time.Millisecond)).UTC()
+		options = append(options, "expiration_timestamp=TIMESTAMP "+quoteString(expiration.Format("2006-01-02 15:04:05.999 MST")))
+	}
+	if labels := table.Options.GetLabels(); len(labels) > 0 {
+		keys := make([]string, 0, len(labels))
+		for k := range labels {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		pairs := make([]string, len(keys))
+		for idx, k := range keys {
+			pairs[idx] = fmt.Sprintf("(%s, %s)", quoteString(k), quoteString(labels[k]))
+		}
+		options = append(options, "labels=["+strings.Join(pairs, ", ")+"]")
+	}
+	return options
+}
+
+// msPerDay is the number of milliseconds in a day, the unit of partition_expiration_days.
+const msPerDay = 24 * 60 * 60 * 1000
+
+// partitionExpression returns the expression of the PARTITION BY clause of a table, or "" if the
+// table is not partitioned.
+func partitionExpression(table *Table) string {
+	if rp := table.Options.GetRangePartitioning(); rp != nil {
+		return fmt.Sprintf("RANGE_BUCKET(%s, GENERATE_ARRAY(%d, %d, %d))", quoteIdentifier(rp.GetField()), rp.GetStart(), rp.GetEnd(), rp.GetInterval())
+	}
+	tp := table.Options.GetTimePartitioning()
+	if tp == nil {
+		return ""
+	}
+	granularity := timePartitioningType(table)
+	if tp.GetField() == "" {
+		if granularity == "DAY" {
+			return "_PARTITIONDATE"
+		}
+		return fmt.Sprintf("TIMESTAMP_TRUNC(_PARTITIONTIME, %s)", granularity)
+	}
+	column := quoteIdentifier(tp.GetField())
+	for _, f := range table.Schema {
+		if !strings.EqualFold(f.Name, tp.GetField()) {
+			continue
+		}
+		switch t := sqlType(f); {
+		case t == "DATE" && granularity == "DAY":
+			return column
+		case granularity == "DAY":
+			return fmt.Sprintf("DATE(%s)", column)
+		default:
+			return fmt.Sprintf("%s_TRUNC(%s, %s)", t, column, granularity)
+		}
+	}
+	return ""
+}
+
+// writeColumns writes the column definitions of a schema, one per line, each one prefixed by indent.
+func writeColumns(b *strings.Builder, schema Schema, indent string) {
+	for idx, f := range schema {
+		b.WriteString(indent)
+		b.WriteString(quoteIdentifier(f.Name))
+		b.WriteString(" ")
+		b.WriteString(columnType(f, indent))
+		if idx < len(schema)-1 {
+			b.WriteString(",")
+		}
+		b.WriteString("\n")
+	}
+}
+
+// columnType returns the type of a column in a column definition, along with its constraints and options.
+func columnType(f *Field, indent string) string {
+	var b strings.Builder
+
+	typ := sqlType(f)
+	if typ == "STRUCT" {
+		var fields strings.Builder
+		writeColumns(&fields, f.Fields, indent+"  ")
+		typ = "STRUCT<\n" + fields.String() + indent + ">"
+	}
+
+	switch f.Mode {
+	case "REPEATED":
+		b.WriteString("ARRAY<" + typ + ">")
+	case "REQUIRED":
+		b.WriteString(typ + " NOT NULL")
+	default:
+		b.WriteString(typ)
+	}
+	if f.Description != "" {
+		b.WriteString(" OPTIONS(description=" + quoteString(f.Description) + ")")
+	}
+	return b.String()
+}
+
+// sqlType returns the GoogleSQL name of the type of a column.
+func sqlType(f *Field) string {
+	if t, ok := sqlTypes[f.Type]; ok {
+		return t
+	}
+	return f.Type
+}
+
+func quoteIdentifier(name string) string {
+	return "`" + name + "`"
+}
+
+// quoteString returns a GoogleSQL string literal, whose escape sequences are a superset of Go's.
+func quoteString(s string) string {
+	return strconv.Quote(s)
+}
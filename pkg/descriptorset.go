@@ -0,0 +1,99 @@
+package pkg
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	descriptor "google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// ReadDescriptorSets reads the FileDescriptorSets written by `protoc -o` or `buf build -o` at the given
+// paths, separated like the paths of the --descriptor_set_in option of protoc, and merges them into one.
+func ReadDescriptorSets(paths string) (*descriptor.FileDescriptorSet, error) {
+	merged := &descriptor.FileDescriptorSet{}
+	seen := make(map[string]bool)
+	for _, path := range filepath.SplitList(paths) {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		set := &descriptor.FileDescriptorSet{}
+		if err = proto.Unmarshal(data, set); err != nil {
+			return nil, fmt.Errorf("cannot parse descriptor set %s: %v", path, err)
+		}
+		for _, file := range set.GetFile() {
+			if !seen[file.GetName()] {
+				seen[file.GetName()] = true
+				merged.File = append(merged.File, file)
+			}
+		}
+	}
+	return merged, nil
+}
+
+// NewRequest returns the CodeGeneratorRequest protoc would send to generate the given targets from the
+// files of a descriptor set. Targets are either the names of files in the set, or the fully-qualified
+// names of top-level messages, in which case the file declaring the message is generated, and the names
+// of the messages are returned to restrict generation to them with WithMessages. Without targets, every
+// file of the set is generated.
+func NewRequest(set *descriptor.FileDescriptorSet, parameter string, targets []string) (*pluginpb.CodeGeneratorRequest, []string, error) {
+	req := &pluginpb.CodeGeneratorRequest{
+		Parameter: proto.String(parameter),
+		ProtoFile: set.GetFile(),
+	}
+
+	files := make(map[string]bool)
+	messages := make(map[string]string)
+	for _, file := range set.GetFile() {
+		files[file.GetName()] = true
+		for _, msg := range file.GetMessageType() {
+			name := msg.GetName()
+			if file.GetPackage() != "" {
+				name = file.GetPackage() + "." + name
+			}
+			messages[name] = file.GetName()
+		}
+	}
+
+	var messageTargets []string
+	generated := make(map[string]bool)
+	for _, target := range targets {
+		file := target
+		if !files[target] {
+			var ok bool
+			if file, ok = messages[strings.TrimPrefix(target, ".")]; !ok {
+				return nil, nil, fmt.Errorf("%s is neither a file nor a top-level message of the descriptor set", target)
+			}
+			messageTargets = append(messageTargets, target)
+		}
+		if !generated[file] {
+			generated[file] = true
+			req.FileToGenerate = append(req.FileToGenerate, file)
+		}
+	}
+	if len(targets) == 0 {
+		for _, file := range set.GetFile() {
+			req.FileToGenerate = append(req.FileToGenerate, file.GetName())
+		}
+	}
+	return req, messageTargets, nil
+}
+
+// WriteFiles writes generated files under the given directory, as protoc does.
+func WriteFiles(dir string, files []*pluginpb.CodeGeneratorResponse_File) error {
+	for _, file := range files {
+		path := filepath.Join(dir, filepath.FromSlash(file.GetName()))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(path, []byte(file.GetContent()), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package pkg
+
+import (
+	"fmt"
+	"strings"
+)
+
+const extraFieldFormat = `"<name>:<type>[:<mode>]" or "<name>:RECORD:<message type>[:<mode>]"`
+
+// parseExtraFields converts the extra_fields of gen_bq_schema.bigquery_opts on the given message into
+// BigQuery fields. Message types of RECORD extra fields are resolved like field types, relative to msg.
+// Names clashing with a field already in schema are rejected.
+func (g *generation) parseExtraFields(msg *ProtoType, specs []string, schema Schema, tr *traversal) (Schema, error) {
+	names := make(map[string]bool)
+	for _, f := range schema {
+		names[strings.ToLower(f.Name)] = true
+	}
+
+	extra := make(Schema, 0, len(specs))
+	for _, spec := range specs {
+		f, err := g.parseExtraField(msg, spec, tr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid extra field %q of %s: %v", spec, strings.TrimPrefix(msg.Name, "."), err)
+		}
+		if names[strings.ToLower(f.Name)] {
+			return nil, fmt.Errorf("invalid extra field %q of %s: a field named %s already exists", spec, strings.TrimPrefix(msg.Name, "."), f.Name)
+		}
+		names[strings.ToLower(f.Name)] = true
+		extra = append(extra, f)
+	}
+	return extra, nil
+}
+
+func (g *generation) parseExtraField(msg *ProtoType, spec string, tr *traversal) (*Field, error) {
+	parts := strings.Split(spec, ":")
+	for idx := range parts {
+		parts[idx] = strings.TrimSpace(parts[idx])
+	}
+	if len(parts) < 2 {
+		return nil, fmt.Errorf("expected %s", extraFieldFormat)
+	}
+
+	name, bqType, rest := parts[0], strings.ToUpper(parts[1]), parts[2:]
+	if !isValidFieldName(name) {
+		return nil, fmt.Errorf("%q is not a valid BigQuery column name", name)
+	}
+	if !bigQueryTypes[bqType] {
+		return nil, fmt.Errorf("unknown BigQuery type %q", parts[1])
+	}
+
+	var typeName string
+	if bqType == "STRUCT" {
+		bqType = "RECORD"
+	}
+	if bqType == "RECORD" {
+		if len(rest) == 0 || rest[0] == "" {
+			return nil, fmt.Errorf("%s fields require a message type, expected %s", bqType, extraFieldFormat)
+		}
+		typeName, rest = rest[0], rest[1:]
+	}
+
+	mode := "NULLABLE"
+	switch len(rest) {
+	case 0:
+	case 1:
+		mode = strings.ToUpper(rest[0])
+		if !bigQueryModes[mode] {
+			return nil, fmt.Errorf("unknown BigQuery mode %q", rest[0])
+		}
+	default:
+		return nil, fmt.Errorf("expected %s", extraFieldFormat)
+	}
+
+	bqField := NewBQField(name, bqType, mode, "")
+	if typeName == "" {
+		return bqField, nil
+	}
+
+	if wkt, ok := getWellKnownType(typeName); ok {
+		bqField.Type = wkt.Type
+		bqField.Fields = wkt.Schema()
+	} else {
+		pt := g.locals.Resolve(msg.Name, typeName)
+		if pt == nil {
+			return nil, fmt.Errorf("cannot resolve message type %s", typeName)
+		}
+		var err error
+		if bqField.Fields, err = g.traverseMessage(pt, tr); err != nil {
+			return nil, err
+		}
+	}
+	if bqField.Type == "RECORD" && len(bqField.Fields) == 0 {
+		return nil, fmt.Errorf("message type %s has no fields", typeName)
+	}
+	return bqField, nil
+}
@@ -2,11 +2,10 @@ package pkg
 
 import (
 	"encoding/json"
-	"flag"
 	"fmt"
 	"io"
 	"io/ioutil"
-	"os"
+	"path"
 	"strings"
 
 	"github.com/GoogleCloudPlatform/protoc-gen-bq-schema/protos"
@@ -17,8 +16,6 @@ import (
 )
 
 var (
-	locals            Locals
-	comments          Comments
 	typeFromFieldType = map[descriptor.FieldDescriptorProto_Type]string{
 		descriptor.FieldDescriptorProto_TYPE_DOUBLE: "FLOAT",
 		descriptor.FieldDescriptorProto_TYPE_FLOAT:  "FLOAT",
@@ -49,11 +46,65 @@ var (
 		descriptor.FieldDescriptorProto_LABEL_REQUIRED: "REQUIRED",
 		descriptor.FieldDescriptorProto_LABEL_REPEATED: "REPEATED",
 	}
+
+	// outputFormats maps the values of the format parameter to how tables are rendered in that format.
+	outputFormats = map[string]struct {
+		extension string
+		render    func(*Table) (string, error)
+	}{
+		formatSchema:    {"schema", renderSchema},
+		formatDDL:       {"sql", renderDDL},
+		formatTable:     {"table.json", renderTable},
+		formatTerraform: {"tf.json", renderTerraform},
+		formatMigration: {"migration.sql", renderMigration},
+	}
 )
 
-func getNested(pkgName string, fieldProto *descriptor.FieldDescriptorProto) *ProtoType {
-	n := strings.Split(fieldProto.GetTypeName(), ".")
-	return locals.GetTypeFromPackage(pkgName, n[len(n)-1])
+// traversal holds the state of converting the message tree of a table into its schema.
+type traversal struct {
+	// parentMessages counts how many times each message is being traversed, to limit recursion.
+	parentMessages map[*descriptor.DescriptorProto]int32
+	// useJSONNames names columns after the JSON names of fields rather than their proto names.
+	useJSONNames bool
+	// mapsAsJSON renders map fields as a single JSON column rather than repeated key/value records.
+	mapsAsJSON bool
+}
+
+// fullFieldName returns the fully-qualified name of a field declared in the given message, for error messages.
+func fullFieldName(msg *ProtoType, fieldProto *descriptor.FieldDescriptorProto) string {
+	return strings.TrimPrefix(msg.Name, ".") + "." + fieldProto.GetName()
+}
+
+// getNested resolves the message type of a field declared in the given message.
+func (g *generation) getNested(msg *ProtoType, fieldProto *descriptor.FieldDescriptorProto) (*ProtoType, error) {
+	pt := g.locals.Resolve(msg.Name, fieldProto.GetTypeName())
+	if pt == nil {
+		return nil, fmt.Errorf("cannot resolve type %s of field %s", fieldProto.GetTypeName(), fullFieldName(msg, fieldProto))
+	}
+	return pt, nil
+}
+
+// jsonName returns the JSON name of a field. protoc always fills in json_name, falling back to
+// the lowerCamelCase form of the field name the same way protoc does when it is missing.
+func jsonName(fieldProto *descriptor.FieldDescriptorProto) string {
+	if fieldProto.JsonName != nil {
+		return fieldProto.GetJsonName()
+	}
+	var b strings.Builder
+	upper := false
+	for _, c := range fieldProto.GetName() {
+		switch {
+		case c == '_':
+			upper = true
+		case upper && 'a' <= c && c <= 'z':
+			b.WriteRune(c - 'a' + 'A')
+			upper = false
+		default:
+			b.WriteRune(c)
+			upper = false
+		}
+	}
+	return b.String()
 }
 
 func IsRecordType(fieldProto *descriptor.FieldDescriptorProto) bool {
@@ -79,132 +130,445 @@ func GetCodeGenRequestResponse(rd io.Reader) (*plugin.CodeGeneratorRequest, *plu
 	return req, resp
 }
 
-func _traverseField(pkgName string, bqField *Field, protoField *descriptor.FieldDescriptorProto, desc *descriptor.DescriptorProto, parentMessages map[*descriptor.DescriptorProto]bool) *Field {
-	if IsRecordType(protoField) {
-		pt := getNested(pkgName, protoField)
-		desc = pt.Type
-		if parentMessages[desc] {
-			glog.Errorf("Detected recursion for message %s, ignoring subfields", desc.GetName())
-			return bqField
+// convertField builds the BigQuery field for the given proto field, applying its
+// gen_bq_schema.bigquery options. It returns nil if the field should not be part of the schema,
+// either because it is ignored or because it is a google.protobuf.Empty.
+func (g *generation) convertField(msg *ProtoType, fieldProto *descriptor.FieldDescriptorProto, comment string, tr *traversal) (*Field, error) {
+	var opts *protos.BigQueryFieldOptions
+	var err error
+
+	if opts, err = getBigqueryFieldOptions(fieldProto); err != nil {
+		return nil, err
+	}
+	opts = g.overrides.fieldOptions(msg, fieldProto, opts)
+	if opts.GetIgnore() {
+		return nil, nil
+	}
+
+	var pt *ProtoType
+	bqType := typeFromFieldType[fieldProto.GetType()]
+	wkt, isWellKnown := getWellKnownType(fieldProto.GetTypeName())
+	isWellKnown = isWellKnown && IsRecordType(fieldProto)
+	if isWellKnown {
+		bqType = wkt.Type
+	} else if IsRecordType(fieldProto) {
+		if pt, err = g.getNested(msg, fieldProto); err != nil {
+			return nil, err
 		}
-		parentMessages[desc] = true
-		for idx, inner := range desc.GetField() {
-			fieldCommentPath := fmt.Sprintf("%s.%d.%d", pt.Path, fieldPath, idx)
-			innerBQField := NewBQField(
-				inner.GetName(),
-				typeFromFieldType[inner.GetType()],
-				modeFromFieldLabel[inner.GetLabel()],
-				comments[fieldCommentPath],
-			)
-			if IsRecordType(inner) {
-				innerBQField = _traverseField(pkgName, innerBQField, inner, desc, parentMessages)
-				if innerBQField != nil {
-					bqField.Fields = append(bqField.Fields, innerBQField)
-				}
-			} else {
-				if innerBQField != nil {
-					bqField.Fields = append(bqField.Fields, innerBQField)
+	}
+	isMap := pt != nil && pt.Type.GetOptions().GetMapEntry()
+	if isMap && tr.mapsAsJSON {
+		bqType = "JSON"
+	}
+	if pt != nil && bqType == "RECORD" && tr.parentMessages[pt.Type] > 0 {
+		var depth int32
+		var fallback protos.RecursionFallback
+		if depth, fallback, err = g.getRecursionLimit(pt); err != nil {
+			return nil, err
+		}
+		if tr.parentMessages[pt.Type] > depth {
+			switch fallback {
+			case protos.RecursionFallback_RECURSION_JSON:
+				bqType = "JSON"
+			case protos.RecursionFallback_RECURSION_STRING:
+				bqType = "STRING"
+			default:
+				glog.Infof("Reached recursion depth of message %s, omitting field %s", pt.Name, fullFieldName(msg, fieldProto))
+				return nil, nil
+			}
+		}
+	}
+	isEnum := fieldProto.GetType() == descriptor.FieldDescriptorProto_TYPE_ENUM
+	if isEnum {
+		bqType = g.enumType(opts)
+	}
+
+	name := fieldProto.GetName()
+	if tr.useJSONNames {
+		name = jsonName(fieldProto)
+	}
+
+	bqField := NewBQField(
+		name,
+		bqType,
+		g.columnMode(msg, fieldProto),
+		comment,
+	)
+	if opts.GetName() != "" {
+		bqField.Name = opts.GetName()
+	}
+	if opts.GetDescription() != "" {
+		bqField.Description = opts.GetDescription()
+	}
+	if isEnum && (opts.GetDescribeEnumValues() || g.params.EnumValues) {
+		enum := g.locals.ResolveEnum(msg.Name, fieldProto.GetTypeName())
+		if enum == nil {
+			return nil, fmt.Errorf("cannot resolve type %s of field %s", fieldProto.GetTypeName(), fullFieldName(msg, fieldProto))
+		}
+		bqField.Description = strings.TrimSpace(bqField.Description + "\n\n" + describeEnumValues(enum))
+	}
+	if opts.GetPolicyTags() != "" {
+		bqField.PolicyTags = &PolicyTags{Names: []string{opts.GetPolicyTags()}}
+	}
+	if opts.GetTypeOverride() != "" {
+		bqField.Type = opts.GetTypeOverride()
+	}
+	if isMap && bqField.Type == "JSON" {
+		// The whole map is a single JSON object rather than a list of entries.
+		bqField.Mode = "NULLABLE"
+	}
+	if opts.GetRequire() && bqField.Mode != "REPEATED" {
+		// BigQuery has no REQUIRED arrays, so repeated fields stay REPEATED.
+		bqField.Mode = "REQUIRED"
+	} else if override := g.overrides.field(msg.Name, fieldProto.GetName()); override != nil && override.Mode == "NULLABLE" {
+		bqField.Mode = "NULLABLE"
+	}
+
+	if isEnum && bqField.Type == "RECORD" {
+		bqField.Fields = Schema{
+			NewBQField("name", "STRING", "NULLABLE", ""),
+			NewBQField("number", "INTEGER", "NULLABLE", ""),
+		}
+	}
+	if IsRecordType(fieldProto) && bqField.Type == "RECORD" {
+		if isWellKnown {
+			bqField.Fields = wkt.Schema()
+		} else {
+			if bqField.Fields, err = g.traverseMessage(pt, tr); err != nil {
+				return nil, err
+			}
+		}
+		if isMap {
+			for _, f := range bqField.Fields {
+				if f.Name == "key" {
+					f.Mode = "REQUIRED"
 				}
 			}
 		}
-		parentMessages[desc] = false
+		// google.protobuf.Empty carries no data. Other messages without fields are kept, for the
+		// validation of the schema to reject them, since BigQuery does not accept empty RECORDs.
+		if isWellKnown && len(bqField.Fields) == 0 {
+			return nil, nil
+		}
 	}
-	return bqField
+	return bqField, nil
 }
 
-func traverseMessage(pkgName string, msg *descriptor.DescriptorProto, path string, parentMessages map[*descriptor.DescriptorProto]bool) Schema {
+func (g *generation) traverseMessage(msg *ProtoType, tr *traversal) (Schema, error) {
 	var bqField *Field
+	var strategy protos.OneofStrategy
+	var err error
+
 	schema := make(Schema, 0)
-	fields := msg.GetField()
-	if parentMessages[msg] {
-		glog.Errorf("Detected recursion for message %s, ignoring subfields", msg.GetName())
-		return nil
-	}
-	parentMessages[msg] = true
-	for idx, fieldProto := range fields {
-		fieldCommentPath := fmt.Sprintf("%s.%d.%d", path, fieldPath, idx)
-		bqField = NewBQField(
-			fieldProto.GetName(),
-			typeFromFieldType[fieldProto.GetType()],
-			modeFromFieldLabel[fieldProto.GetLabel()],
-			comments[fieldCommentPath],
-		)
-		if IsRecordType(fieldProto) {
-			bqField = _traverseField(pkgName, bqField, fieldProto, getNested(pkgName, fieldProto).Type, parentMessages)
+	if strategy, err = g.getOneofStrategy(msg); err != nil {
+		return nil, err
+	}
+	tr.parentMessages[msg.Type]++
+	oneofs := make(map[int32]*Field)
+	for idx, fieldProto := range msg.Type.GetField() {
+		fieldCommentPath := fmt.Sprintf("%s.%d.%d", msg.Path, fieldPath, idx)
+		if bqField, err = g.convertField(msg, fieldProto, msg.Comments[fieldCommentPath], tr); err != nil {
+			return nil, err
 		}
-		if bqField != nil {
+		if bqField == nil {
+			continue
+		}
+		if !isRealOneofMember(fieldProto) || strategy == protos.OneofStrategy_ONEOF_FLATTEN {
 			schema = append(schema, bqField)
+			continue
+		}
+
+		// The column standing for the oneof goes where its first member would have been.
+		oneofIdx := fieldProto.GetOneofIndex()
+		oneof, ok := oneofs[oneofIdx]
+		if !ok {
+			oneof = newOneofField(msg, oneofIdx, strategy, tr)
+			oneofs[oneofIdx] = oneof
+			schema = append(schema, oneof)
+		}
+		if strategy == protos.OneofStrategy_ONEOF_WRAP {
+			oneof.Fields = append(oneof.Fields, bqField)
+		} else {
+			schema = append(schema, bqField)
+		}
+	}
+	tr.parentMessages[msg.Type]--
+	return schema, nil
+}
+
+// enumType returns the BigQuery type of an enum field, following the enum format set in its
+// options or with the enums plugin parameter.
+func (g *generation) enumType(opts *protos.BigQueryFieldOptions) string {
+	format := opts.GetEnumFormat()
+	if format == protos.EnumFormat_ENUM_FORMAT_UNSPECIFIED {
+		format = g.params.Enums
+	}
+	switch format {
+	case protos.EnumFormat_ENUM_INTEGER:
+		return "INTEGER"
+	case protos.EnumFormat_ENUM_NAME_AND_NUMBER:
+		return "RECORD"
+	default:
+		return "STRING"
+	}
+}
+
+// describeEnumValues lists the values of an enum for the description of a column.
+func describeEnumValues(enum *descriptor.EnumDescriptorProto) string {
+	values := make([]string, 0, len(enum.GetValue()))
+	for _, v := range enum.GetValue() {
+		values = append(values, fmt.Sprintf("%s = %d", v.GetName(), v.GetNumber()))
+	}
+	return "Allowed values: " + strings.Join(values, ", ")
+}
+
+// isRealOneofMember reports whether the field is a member of a oneof declared in the proto file,
+// rather than of the synthetic oneof of a proto3 optional field.
+func isRealOneofMember(fieldProto *descriptor.FieldDescriptorProto) bool {
+	return fieldProto.OneofIndex != nil && !fieldProto.GetProto3Optional()
+}
+
+// getOneofStrategy returns how the oneofs of the message are rendered, taken from the first of the
+// message options, the file options and the oneofs plugin parameter which sets it.
+func (g *generation) getOneofStrategy(msg *ProtoType) (protos.OneofStrategy, error) {
+	msgOpts, err := getBigqueryMessageOptions(msg.Type)
+	if err != nil {
+		return 0, err
+	}
+	fileOpts, err := getBigqueryFileOptions(msg.File)
+	if err != nil {
+		return 0, err
+	}
+	for _, strategy := range []protos.OneofStrategy{
+		msgOpts.GetOneofStrategy(),
+		fileOpts.GetOneofStrategy(),
+		g.params.Oneofs,
+	} {
+		if strategy != protos.OneofStrategy_ONEOF_STRATEGY_UNSPECIFIED {
+			return strategy, nil
 		}
 	}
-	parentMessages[msg] = false
-	return schema
+	return protos.OneofStrategy_ONEOF_FLATTEN, nil
+}
+
+// getRecursionLimit returns how many times a recursive message is expanded within itself, and how
+// its fields are rendered past that, taken from the message options or the plugin parameters.
+func (g *generation) getRecursionLimit(msg *ProtoType) (int32, protos.RecursionFallback, error) {
+	opts, err := getBigqueryMessageOptions(msg.Type)
+	if err != nil {
+		return 0, 0, err
+	}
+	depth := opts.GetRecursionDepth()
+	if depth == 0 {
+		depth = g.params.RecursionDepth
+	}
+	fallback := opts.GetRecursionFallback()
+	if fallback == protos.RecursionFallback_RECURSION_FALLBACK_UNSPECIFIED {
+		fallback = g.params.RecursionFallback
+	}
+	return depth, fallback, nil
 }
 
-func getFileForResponse(pkgName string, msg *descriptor.DescriptorProto, path string, parentMessages map[*descriptor.DescriptorProto]bool) (*plugin.CodeGeneratorResponse_File, error) {
+// newOneofField returns the column standing for a oneof: a RECORD holding its members when
+// wrapping, or the STRING column holding the name of the member which is set otherwise.
+func newOneofField(msg *ProtoType, oneofIdx int32, strategy protos.OneofStrategy, tr *traversal) *Field {
+	oneof := msg.Type.GetOneofDecl()[oneofIdx]
+	name := oneof.GetName()
+	if tr.useJSONNames {
+		name = jsonName(&descriptor.FieldDescriptorProto{Name: oneof.Name})
+	}
+	comment := msg.Comments[fmt.Sprintf("%s.%d.%d", msg.Path, oneofPath, oneofIdx)]
+	if strategy == protos.OneofStrategy_ONEOF_WRAP {
+		return NewBQField(name, "RECORD", "NULLABLE", comment)
+	}
+	return NewBQField(name, "STRING", "NULLABLE", comment)
+}
+
+// getTable converts a message into the BigQuery table it is stored in. Messages without a
+// gen_bq_schema.bigquery_opts table name are not stored into BigQuery, in which case it returns nil.
+func (g *generation) getTable(msg *ProtoType) (*Table, error) {
 	var opts *protos.BigQueryMessageOptions
-	var jsonSchema []byte
+	var schema, extra Schema
 	var err error
 
-	if opts, err = getBigqueryMessageOptions(msg); err != nil {
+	if opts, err = getBigqueryMessageOptions(msg.Type); err != nil {
+		return nil, err
+	}
+	opts = g.overrides.messageOptions(msg, opts)
+	if opts.GetTableName() == "" {
+		var name string
+		if g.params.SingleMessage {
+			// The root message of the file, since getFilesForResponse generates no other.
+			name = strings.TrimSuffix(path.Base(msg.File.GetName()), ".proto")
+		} else if name, err = g.includedTableName(msg); err != nil {
+			return nil, err
+		} else if name == "" && g.messages[msg.Name] {
+			return nil, fmt.Errorf("message %s is selected but has no table name, set the table_name option or include it", strings.TrimPrefix(msg.Name, "."))
+		} else if name == "" {
+			return nil, nil
+		}
+		if opts == nil {
+			opts = &protos.BigQueryMessageOptions{}
+		} else {
+			opts = proto.Clone(opts).(*protos.BigQueryMessageOptions)
+		}
+		opts.TableName = name
+	}
+	tr := &traversal{
+		parentMessages: map[*descriptor.DescriptorProto]int32{},
+		useJSONNames:   opts.GetUseJsonNames(),
+		mapsAsJSON:     g.params.Maps == mapsJSON,
+	}
+	if schema, err = g.traverseMessage(msg, tr); err != nil {
+		return nil, err
+	}
+	if extra, err = g.parseExtraFields(msg, opts.GetExtraFields(), schema, tr); err != nil {
 		return nil, err
 	}
-	tableName := opts.GetTableName()
-	schema := traverseMessage(pkgName, msg, path, parentMessages)
 
-	if jsonSchema, err = json.MarshalIndent(schema, "", " "); err != nil {
+	description := opts.GetDescription()
+	if description == "" {
+		description = msg.Comments.Get(msg.Path)
+	}
+	table := &Table{
+		Project:     g.params.Project,
+		Dataset:     g.params.Dataset,
+		Name:        opts.GetTableName(),
+		Description: description,
+		Schema:      append(schema, extra...),
+		Options:     opts,
+	}
+	if err = validateSchema(msg, table); err != nil {
 		return nil, err
 	}
-	resFile := &plugin.CodeGeneratorResponse_File{
-		Name:    proto.String(fmt.Sprintf("%s/%s.schema", strings.Replace(pkgName, ".", "/", -1), tableName)),
-		Content: proto.String(string(jsonSchema)),
+	if err = validatePartitioning(table); err != nil {
+		return nil, err
+	}
+	return table, nil
+}
+
+// renderSchema renders the schema of a table as the JSON accepted by `bq load` and `bq mk`.
+func renderSchema(table *Table) (string, error) {
+	jsonSchema, err := json.MarshalIndent(table.Schema, "", " ")
+	if err != nil {
+		return "", err
 	}
-	return resFile, nil
+	return string(jsonSchema), nil
 }
 
-func getFilesForResponse(file *descriptor.FileDescriptorProto) ([]*plugin.CodeGeneratorResponse_File, error) {
-	var f *plugin.CodeGeneratorResponse_File
+// getFilesForMessage generates the files for a message in the output format selected with the
+// format parameter. Along with JSON schemas, it generates the table resource of tables which have
+// options a schema cannot express. Messages which are not stored into BigQuery, or whose rendering is
+// empty, get no file.
+func (g *generation) getFilesForMessage(pkgName string, msg *ProtoType) ([]*plugin.CodeGeneratorResponse_File, error) {
+	var table *Table
+	var content string
 	var err error
 
-	responseFiles := make([]*plugin.CodeGeneratorResponse_File, 0)
-	for _, msg := range file.GetMessageType() {
-		pt := locals.GetTypeFromPackage(file.GetPackage(), msg.GetName())
-		if f, err = getFileForResponse(file.GetPackage(), msg, pt.Path, map[*descriptor.DescriptorProto]bool{}); err != nil {
+	if table, err = g.getTable(msg); err != nil || table == nil {
+		return nil, err
+	}
+	var schemaPath string
+	if schemaPath, err = g.outputPath(pkgName, msg, table, outputFormats[formatSchema].extension); err != nil {
+		return nil, err
+	}
+	if err = g.checkCompatibility(schemaPath, table); err != nil {
+		return nil, err
+	}
+
+	formats := []string{g.params.Format}
+	if g.params.Format == formatSchema && table.hasResourceOptions() {
+		formats = append(formats, formatTable)
+	}
+	var resFiles []*plugin.CodeGeneratorResponse_File
+	for _, name := range formats {
+		var fileName string
+		format := outputFormats[name]
+		if content, err = format.render(table); err != nil || content == "" {
+			return resFiles, err
+		}
+		if fileName, err = g.outputPath(pkgName, msg, table, format.extension); err != nil {
+			return nil, err
+		}
+		if err = g.claimOutput(fileName, msg); err != nil {
 			return nil, err
 		}
-		responseFiles = append(responseFiles, f)
+		resFiles = append(resFiles, &plugin.CodeGeneratorResponse_File{
+			Name:    proto.String(fileName),
+			Content: proto.String(content),
+		})
 	}
-	return responseFiles, nil
+	return resFiles, nil
 }
 
-func writeResp(res *plugin.CodeGeneratorResponse) {
-	var data []byte
+func (g *generation) getFilesForResponse(file *descriptor.FileDescriptorProto) ([]*plugin.CodeGeneratorResponse_File, error) {
+	var files []*plugin.CodeGeneratorResponse_File
 	var err error
 
-	if data, err = proto.Marshal(res); err != nil {
-		glog.Errorf("cannot marshal response: %v", err)
+	// Files declaring messages selected with WithMessages only get the files of those messages.
+	selected := make(map[string]bool)
+	for _, msg := range file.GetMessageType() {
+		if pt := g.locals.GetTypeFromPackage(file.GetPackage(), msg.GetName()); g.messages[pt.Name] {
+			selected[pt.Name] = true
+		}
+	}
+
+	// With single-message, only the root message of the file is generated.
+	var root *ProtoType
+	if g.params.SingleMessage {
+		if root, err = g.singleMessageRoot(file); err != nil || root == nil {
+			return nil, err
+		}
+	}
+
+	responseFiles := make([]*plugin.CodeGeneratorResponse_File, 0)
+	for _, msg := range file.GetMessageType() {
+		pt := g.locals.GetTypeFromPackage(file.GetPackage(), msg.GetName())
+		if len(selected) > 0 && !selected[pt.Name] || root != nil && pt != root {
+			continue
+		}
+		if files, err = g.getFilesForMessage(g.outputPackage(file), pt); err != nil {
+			return nil, err
+		}
+		responseFiles = append(responseFiles, files...)
 	}
-	if _, err = os.Stdout.Write(data); err != nil {
-		glog.Errorf("failed to write response: %v", err)
+	return responseFiles, nil
+}
+
+// outputPackage returns the package the tables of a file are generated in: the package mapped to the
+// file with an M parameter, or else the package declared by the file.
+func (g *generation) outputPackage(file *descriptor.FileDescriptorProto) string {
+	if pkg, ok := g.params.Packages[file.GetName()]; ok {
+		return pkg
 	}
+	return file.GetPackage()
 }
 
-// handleSingleMessageOpt handles --bq-schema_opt=single-message in protoc params.
-// providing that param tells protoc-gen-bq-schema to treat each proto files only contains one top-level type.
-// if a file contains no message packages, then this function simply does nothing.
-// if a file contains more than one message packages, then only the first message type will be processed.
-// in that case, the table names will follow the proto file names.
-func handleSingleMessageOpt(file *descriptor.FileDescriptorProto, requestParam string) {
-	glog.Info("handleSingleMessageOpt")
-	if !strings.Contains(requestParam, "single-message") || len(file.GetMessageType()) == 0 {
-		return
-	}
-	file.MessageType = file.GetMessageType()[:1]
-	message := file.GetMessageType()[0]
-	message.Options = &descriptor.MessageOptions{}
-	fileName := file.GetName()
-	proto.SetExtension(message.GetOptions(), protos.E_BigqueryOpts, &protos.BigQueryMessageOptions{
-		TableName: fileName[strings.LastIndexByte(fileName, '/')+1 : strings.LastIndexByte(fileName, '.')],
-	})
+// singleMessageRoot returns the only message generated from a file with the single-message parameter: the
+// message named with the root-message parameter, which the file must declare, or else the first message with
+// a table name, or else the first message. It returns nil if the file declares no message.
+func (g *generation) singleMessageRoot(file *descriptor.FileDescriptorProto) (*ProtoType, error) {
+	var annotated *ProtoType
+	for _, msg := range file.GetMessageType() {
+		pt := g.locals.GetTypeFromPackage(file.GetPackage(), msg.GetName())
+		if root := g.params.RootMessage; root != "" && (root == msg.GetName() || root == strings.TrimPrefix(pt.Name, ".")) {
+			return pt, nil
+		}
+		opts, err := getBigqueryMessageOptions(msg)
+		if err != nil {
+			return nil, err
+		}
+		if annotated == nil && g.overrides.messageOptions(pt, opts).GetTableName() != "" {
+			annotated = pt
+		}
+	}
+	if g.params.RootMessage != "" {
+		return nil, fmt.Errorf("root message %s is not a top-level message of the file", g.params.RootMessage)
+	}
+	if annotated != nil || len(file.GetMessageType()) == 0 {
+		return annotated, nil
+	}
+	return g.locals.GetTypeFromPackage(file.GetPackage(), file.GetMessageType()[0].GetName()), nil
 }
 
 // getBigqueryMessageOptions returns the bigquery options for the given message.
@@ -225,37 +589,36 @@ func getBigqueryMessageOptions(msg *descriptor.DescriptorProto) (*protos.BigQuer
 	return proto.GetExtension(options, protos.E_BigqueryOpts).(*protos.BigQueryMessageOptions), nil
 }
 
-func Do() {
-	var req *plugin.CodeGeneratorRequest
-	var res *plugin.CodeGeneratorResponse
-	var converted []*plugin.CodeGeneratorResponse_File
-
-	var err error
+// getBigqueryFileOptions returns the bigquery options for the given file.
+// If an error is encountered, it is returned instead. If no error occurs, but
+// the file has no gen_bq_schema.bigquery_file_opts option, this function returns
+// nil, nil.
+func getBigqueryFileOptions(file *descriptor.FileDescriptorProto) (*protos.BigQueryFileOptions, error) {
+	options := file.GetOptions()
+	if options == nil {
+		return nil, nil
+	}
 
-	flag.Parse()
-	if req, res = GetCodeGenRequestResponse(os.Stdin); res.Error != nil {
-		return
+	if !proto.HasExtension(options, protos.E_BigqueryFileOpts) {
+		return nil, nil
 	}
-	locals = InitLocals(req)
-	generateTargets := make(map[string]bool)
-	for _, file := range req.GetFileToGenerate() {
-		generateTargets[file] = true
+
+	return proto.GetExtension(options, protos.E_BigqueryFileOpts).(*protos.BigQueryFileOptions), nil
+}
+
+// getBigqueryFieldOptions returns the bigquery options for the given field.
+// If an error is encountered, it is returned instead. If no error occurs, but
+// the field has no gen_bq_schema.bigquery option, this function returns
+// nil, nil.
+func getBigqueryFieldOptions(field *descriptor.FieldDescriptorProto) (*protos.BigQueryFieldOptions, error) {
+	options := field.GetOptions()
+	if options == nil {
+		return nil, nil
 	}
 
-	params := ParseRequestOptions(req.GetParameter())
-	for _, file := range req.GetProtoFile() {
-		comments = ParseComments(file)
-		handleSingleMessageOpt(file, req.GetParameter())
-		if _, ok := params[file.GetName()]; file.GetPackage() == "" && ok {
-			file.Package = proto.String(params[file.GetName()])
-		}
-		if _, ok := generateTargets[file.GetName()]; ok {
-			if converted, err = getFilesForResponse(file); err != nil {
-				res.Error = proto.String(fmt.Sprintf("Failed to convert %s: %v", file.GetName(), err))
-			}
-			res.File = append(res.File, converted...)
-		}
+	if !proto.HasExtension(options, protos.E_Bigquery) {
+		return nil, nil
 	}
 
-	writeResp(res)
+	return proto.GetExtension(options, protos.E_Bigquery).(*protos.BigQueryFieldOptions), nil
 }
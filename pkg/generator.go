@@ -0,0 +1,142 @@
+package pkg
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/golang/glog"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// Generator generates BigQuery schemas from the messages of proto files. A Generator holds no state
+// between requests, so it can generate several requests concurrently.
+type Generator struct {
+	// parameter holds the default parameters, in the syntax of CodeGeneratorRequest.parameter.
+	parameter string
+	// messages holds the fully-qualified names of the messages to generate, with a leading dot.
+	messages map[string]bool
+}
+
+// Option configures a Generator.
+type Option func(*Generator)
+
+// WithParameter sets default parameters, e.g. "format=ddl,dataset=analytics", in the syntax of
+// --bq-schema_opt. The parameters of requests take precedence over them.
+func WithParameter(parameter string) Option {
+	return func(g *Generator) {
+		if g.parameter != "" && parameter != "" {
+			g.parameter += ","
+		}
+		g.parameter += parameter
+	}
+}
+
+// WithMessages restricts generation to the top-level messages with the given fully-qualified names, in
+// the files declaring them. Other files are generated in full.
+func WithMessages(names ...string) Option {
+	return func(g *Generator) {
+		if g.messages == nil {
+			g.messages = make(map[string]bool)
+		}
+		for _, name := range names {
+			g.messages["."+strings.TrimPrefix(name, ".")] = true
+		}
+	}
+}
+
+// NewGenerator returns a Generator configured with the given options.
+func NewGenerator(opts ...Option) *Generator {
+	g := &Generator{}
+	for _, opt := range opts {
+		opt(g)
+	}
+	return g
+}
+
+// generation holds the state of generating the files of a single request.
+type generation struct {
+	locals    Locals
+	params    *Parameters
+	overrides overrides
+	messages  map[string]bool
+	// outputs maps the paths of the generated files to the fully-qualified names of their messages.
+	outputs map[string]string
+}
+
+// Generate generates the files for the proto files to generate of a request. Problems with the
+// proto files or the parameters are reported in the Error of the response, as protoc expects, while
+// an error is returned if no response could be generated at all. The request is left untouched.
+func (g *Generator) Generate(req *pluginpb.CodeGeneratorRequest) (*pluginpb.CodeGeneratorResponse, error) {
+	if req == nil {
+		return nil, fmt.Errorf("no request to generate")
+	}
+	req = proto.Clone(req).(*pluginpb.CodeGeneratorRequest)
+	if g.parameter != "" {
+		parameter := g.parameter
+		if req.GetParameter() != "" {
+			parameter += "," + req.GetParameter()
+		}
+		req.Parameter = proto.String(parameter)
+	}
+
+	res := &pluginpb.CodeGeneratorResponse{}
+	params, err := ParseParameters(req.GetParameter())
+	if err != nil {
+		res.Error = proto.String(err.Error())
+		return res, nil
+	}
+	gen := &generation{
+		locals:   initLocals(req),
+		params:   params,
+		messages: g.messages,
+		outputs:  make(map[string]string),
+	}
+	if gen.overrides, err = readOverrides(params.Overrides); err == nil {
+		err = gen.overrides.check(&gen.locals)
+	}
+	if err != nil {
+		res.Error = proto.String(err.Error())
+		return res, nil
+	}
+
+	generateTargets := make(map[string]bool)
+	for _, file := range req.GetFileToGenerate() {
+		generateTargets[file] = true
+	}
+	for _, file := range req.GetProtoFile() {
+		if _, ok := generateTargets[file.GetName()]; ok {
+			converted, err := gen.getFilesForResponse(file)
+			if err != nil {
+				res.Error = proto.String(fmt.Sprintf("Failed to convert %s: %v", file.GetName(), err))
+			}
+			res.File = append(res.File, converted...)
+		}
+	}
+	return res, nil
+}
+
+// Do runs the plugin: it reads a CodeGeneratorRequest from the standard input, generates it with the
+// default Generator, and writes the response to the standard output.
+//
+// Deprecated: use NewGenerator().Generate, which takes the request and returns the response.
+func Do() {
+	flag.Parse()
+	req, res := GetCodeGenRequestResponse(os.Stdin)
+	if res.Error == nil {
+		var err error
+		if res, err = NewGenerator().Generate(req); err != nil {
+			glog.Exitf("cannot generate schemas: %v", err)
+		}
+	}
+
+	data, err := proto.Marshal(res)
+	if err != nil {
+		glog.Exitf("cannot marshal response: %v", err)
+	}
+	if _, err = os.Stdout.Write(data); err != nil {
+		glog.Exitf("failed to write response: %v", err)
+	}
+}
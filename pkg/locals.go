@@ -5,58 +5,35 @@ import (
 	"strings"
 
 	plugin "github.com/golang/protobuf/protoc-gen-go/plugin"
-	"google.golang.org/protobuf/proto"
 	descriptor "google.golang.org/protobuf/types/descriptorpb"
 )
 
 // ProtoPackage describes a package of Protobuf, which is an container of message packages.
 type ProtoPackage struct {
-	Name     string
-	parent   *ProtoPackage
-	types    []*descriptor.DescriptorProto
-	Index    map[string]*ProtoType
-	Index2   map[string]*descriptor.DescriptorProto
-	enums    map[string]*descriptor.EnumDescriptorProto
-	comments map[string]Comments
+	Name string
+	// Index holds the messages of the package, keyed by their name relative to the package,
+	// e.g. "Outer.Inner" for a message Inner nested in Outer.
+	Index map[string]*ProtoType
 }
 
+// ProtoType describes a message type along with where it was declared.
 type ProtoType struct {
 	Type *descriptor.DescriptorProto
+	// Path is the path of the message in the FileDescriptorProto declaring it, used to look up comments.
 	Path string
+	// Name is the fully-qualified name of the message, with a leading dot as in FieldDescriptorProto.type_name.
+	Name     string
+	File     *descriptor.FileDescriptorProto
+	Comments Comments
 }
 
 func (p *ProtoPackage) Get(typeName string) *ProtoType {
-	n := strings.Split(typeName, ".")
-	return p.Index[n[len(n)-1]]
-}
-
-func (p *ProtoPackage) _traverse(path string, types []*descriptor.DescriptorProto) {
-	for nestedIdx, nestedDesc := range types {
-		innerPath := fmt.Sprintf("%s.%d.%d", path, subMessagePath, nestedIdx)
-		nestedPT := &ProtoType{
-			Type: nestedDesc,
-			Path: innerPath,
-		}
-		p.Index[nestedDesc.GetName()] = nestedPT
-		p._traverse(innerPath, nestedDesc.GetNestedType())
-	}
-}
-
-func (p *ProtoPackage) traverse() {
-	for idx, desc := range p.types {
-		path := fmt.Sprintf("%d.%d", messagePath, idx)
-		pt := &ProtoType{
-			Type: desc,
-			Path: path,
-		}
-		p.Index[desc.GetName()] = pt
-
-		p._traverse(path, desc.GetNestedType())
-	}
+	return p.Index[typeName]
 }
 
 type Locals struct {
 	packages map[string]*ProtoPackage
+	types    map[string]*ProtoType
 	enums    map[string]*descriptor.EnumDescriptorProto
 }
 
@@ -69,34 +46,119 @@ func (l *Locals) GetPackage(key string) (value *ProtoPackage) {
 }
 
 func (l *Locals) GetTypeFromPackage(pkgName, key string) (value *ProtoType) {
-	tmp := l.GetPackage(pkgName).Get(key)
-	return tmp
+	pkg := l.GetPackage(pkgName)
+	if pkg == nil {
+		return nil
+	}
+	return pkg.Get(key)
+}
+
+// GetType returns the message with the given fully-qualified name, or nil if it is unknown.
+func (l *Locals) GetType(fullName string) *ProtoType {
+	return l.types["."+strings.TrimPrefix(fullName, ".")]
+}
+
+// GetEnum returns the enum with the given fully-qualified name, or nil if it is unknown.
+func (l *Locals) GetEnum(fullName string) *descriptor.EnumDescriptorProto {
+	return l.enums["."+strings.TrimPrefix(fullName, ".")]
+}
+
+// Resolve looks up a message type name as referenced from within the given scope, which is the
+// fully-qualified name of the referencing message. Fully-qualified names (with a leading dot)
+// are looked up as is; relative names are searched from the innermost scope outwards, the same
+// way protoc resolves them.
+func (l *Locals) Resolve(scope, typeName string) *ProtoType {
+	if fullName, ok := resolve(scope, typeName, func(name string) bool { return l.GetType(name) != nil }); ok {
+		return l.GetType(fullName)
+	}
+	return nil
+}
+
+// ResolveEnum looks up an enum type name as referenced from within the given scope, like Resolve.
+func (l *Locals) ResolveEnum(scope, typeName string) *descriptor.EnumDescriptorProto {
+	if fullName, ok := resolve(scope, typeName, func(name string) bool { return l.GetEnum(name) != nil }); ok {
+		return l.GetEnum(fullName)
+	}
+	return nil
 }
 
-func InitLocals(req *plugin.CodeGeneratorRequest) Locals {
+func resolve(scope, typeName string, exists func(fullName string) bool) (string, bool) {
+	if strings.HasPrefix(typeName, ".") {
+		return typeName, exists(typeName)
+	}
+	scope = strings.TrimPrefix(scope, ".")
+	for {
+		candidate := typeName
+		if scope != "" {
+			candidate = scope + "." + typeName
+		}
+		if exists(candidate) {
+			return candidate, true
+		}
+		if scope == "" {
+			return "", false
+		}
+		if idx := strings.LastIndexByte(scope, '.'); idx >= 0 {
+			scope = scope[:idx]
+		} else {
+			scope = ""
+		}
+	}
+}
+
+func (l *Locals) add(file *descriptor.FileDescriptorProto, comments Comments, prefix, path string, desc *descriptor.DescriptorProto) {
+	relName := desc.GetName()
+	if prefix != "" {
+		relName = prefix + "." + desc.GetName()
+	}
+	fullName := "." + relName
+	if file.GetPackage() != "" {
+		fullName = "." + file.GetPackage() + "." + relName
+	}
+
+	pt := &ProtoType{
+		Type:     desc,
+		Path:     path,
+		Name:     fullName,
+		File:     file,
+		Comments: comments,
+	}
+	l.types[fullName] = pt
+	l.GetPackage(file.GetPackage()).Index[relName] = pt
+
+	for _, enum := range desc.GetEnumType() {
+		l.enums[fullName+"."+enum.GetName()] = enum
+	}
+	for nestedIdx, nestedDesc := range desc.GetNestedType() {
+		l.add(file, comments, relName, fmt.Sprintf("%s.%d.%d", path, subMessagePath, nestedIdx), nestedDesc)
+	}
+}
+
+// initLocals indexes the messages and enums of the proto files of a request under the packages the files
+// declare, which type names refer to. Packages mapped with M parameters only apply to the outputs.
+func initLocals(req *plugin.CodeGeneratorRequest) Locals {
 	l := Locals{
 		packages: make(map[string]*ProtoPackage, 0),
+		types:    make(map[string]*ProtoType, 0),
 		enums:    make(map[string]*descriptor.EnumDescriptorProto, 0),
 	}
-	params := ParseRequestOptions(req.GetParameter())
 	for _, file := range req.GetProtoFile() {
-		handleSingleMessageOpt(file, req.GetParameter())
-		if _, ok := params[file.GetName()]; ok {
-			file.Package = proto.String(params[file.GetName()])
-		}
 		if pkg := l.GetPackage(file.GetPackage()); pkg == nil {
-			pkg = &ProtoPackage{
-				Name:     file.GetPackage(),
-				parent:   nil,
-				types:    file.GetMessageType(),
-				comments: make(map[string]Comments),
-				Index:    map[string]*ProtoType{},
-				Index2:   map[string]*descriptor.DescriptorProto{},
+			l.Set(file.GetPackage(), &ProtoPackage{
+				Name:  file.GetPackage(),
+				Index: map[string]*ProtoType{},
+			})
+		}
+		for _, enum := range file.GetEnumType() {
+			if file.GetPackage() == "" {
+				l.enums["."+enum.GetName()] = enum
+			} else {
+				l.enums["."+file.GetPackage()+"."+enum.GetName()] = enum
 			}
-			l.Set(file.GetPackage(), pkg)
 		}
-		for _, protoPackage := range l.packages {
-			protoPackage.traverse()
+		comments := ParseComments(file)
+		for idx, desc := range file.GetMessageType() {
+			l.add(file, comments, "", fmt.Sprintf("%d.%d", messagePath, idx), desc)
 		}
 	}
 	return l
@@ -0,0 +1,113 @@
+package pkg
+
+import (
+	"fmt"
+	"strings"
+)
+
+// renderMigration renders the DDL statements migrating a table from its previous schema to its current
+// one. New tables are created, and tables without changes get no statement. Changes which DDL cannot make,
+// e.g. to policy tags, are listed in comments.
+func renderMigration(table *Table) (string, error) {
+	if table.Previous == nil {
+		return renderDDL(table)
+	}
+
+	var b strings.Builder
+	alter := "ALTER TABLE " + quoteIdentifier(table.QualifiedName())
+	previous := make(map[string]*Field, len(table.Previous))
+	for _, f := range table.Previous {
+		previous[strings.ToLower(f.Name)] = f
+	}
+	for _, cur := range table.Schema {
+		column := quoteIdentifier(cur.Name)
+		prev, ok := previous[strings.ToLower(cur.Name)]
+		if !ok {
+			fmt.Fprintf(&b, "%s ADD COLUMN IF NOT EXISTS %s %s;\n", alter, column, columnType(cur, ""))
+			if cur.PolicyTags != nil {
+				fmt.Fprintf(&b, "-- policy tags of column %s must be set to %s\n", cur.Name, policyTagNames(cur))
+			}
+			continue
+		}
+
+		if sqlType(prev) != sqlType(cur) || structChanged(prev.Fields, cur.Fields) {
+			fmt.Fprintf(&b, "%s ALTER COLUMN %s SET DATA TYPE %s;\n", alter, column, dataType(cur))
+		}
+		if fieldMode(prev) == "REQUIRED" && fieldMode(cur) != "REQUIRED" {
+			fmt.Fprintf(&b, "%s ALTER COLUMN %s DROP NOT NULL;\n", alter, column)
+		}
+		if prev.Description != cur.Description {
+			description := "NULL"
+			if cur.Description != "" {
+				description = quoteString(cur.Description)
+			}
+			fmt.Fprintf(&b, "%s ALTER COLUMN %s SET OPTIONS(description=%s);\n", alter, column, description)
+		}
+		if policyTagNames(prev) != policyTagNames(cur) {
+			fmt.Fprintf(&b, "-- policy tags of column %s must be changed from %s to %s\n", cur.Name, policyTagNames(prev), policyTagNames(cur))
+		}
+		writeNestedChanges(&b, cur.Name+".", prev.Fields, cur.Fields)
+	}
+	return b.String(), nil
+}
+
+// structChanged reports whether the type of a STRUCT column changed between two versions of its fields:
+// whether a field was added, or a field changed type or mode.
+func structChanged(previous, current Schema) bool {
+	fields := make(map[string]*Field, len(previous))
+	for _, f := range previous {
+		fields[strings.ToLower(f.Name)] = f
+	}
+	for _, cur := range current {
+		prev, ok := fields[strings.ToLower(cur.Name)]
+		if !ok || sqlType(prev) != sqlType(cur) || fieldMode(prev) != fieldMode(cur) || structChanged(prev.Fields, cur.Fields) {
+			return true
+		}
+	}
+	return false
+}
+
+// writeNestedChanges writes comments listing the changes to the descriptions and policy tags of the nested
+// fields of a column, which DDL cannot make. The names of the fields are prefixed with prefix.
+func writeNestedChanges(b *strings.Builder, prefix string, previous, current Schema) {
+	fields := make(map[string]*Field, len(previous))
+	for _, f := range previous {
+		fields[strings.ToLower(f.Name)] = f
+	}
+	for _, cur := range current {
+		prev, ok := fields[strings.ToLower(cur.Name)]
+		if !ok {
+			// The descriptions of added fields are part of the STRUCT type they are added with.
+			prev = &Field{Description: cur.Description}
+		}
+		if prev.Description != cur.Description {
+			fmt.Fprintf(b, "-- description of field %s%s must be changed to %s\n", prefix, cur.Name, quoteString(cur.Description))
+		}
+		if policyTagNames(prev) != policyTagNames(cur) {
+			fmt.Fprintf(b, "-- policy tags of field %s%s must be changed from %s to %s\n", prefix, cur.Name, policyTagNames(prev), policyTagNames(cur))
+		}
+		writeNestedChanges(b, prefix+cur.Name+".", prev.Fields, cur.Fields)
+	}
+}
+
+// dataType returns the data type of a column, without its constraints and options.
+func dataType(f *Field) string {
+	typ := sqlType(f)
+	if typ == "STRUCT" {
+		var fields strings.Builder
+		writeColumns(&fields, f.Fields, "  ")
+		typ = "STRUCT<\n" + fields.String() + ">"
+	}
+	if f.Mode == "REPEATED" {
+		typ = "ARRAY<" + typ + ">"
+	}
+	return typ
+}
+
+// policyTagNames returns the policy tags of a column as a list, e.g. "[a, b]".
+func policyTagNames(f *Field) string {
+	if f.PolicyTags == nil {
+		return "[]"
+	}
+	return "[" + strings.Join(f.PolicyTags.Names, ", ") + "]"
+}
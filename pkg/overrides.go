@@ -0,0 +1,187 @@
+package pkg
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/protoc-gen-bq-schema/protos"
+	"google.golang.org/protobuf/proto"
+	descriptor "google.golang.org/protobuf/types/descriptorpb"
+	"sigs.k8s.io/yaml"
+)
+
+// overrides holds the options of messages and fields read from the file given with the overrides
+// parameter, for protos which cannot be annotated with gen_bq_schema options. It is keyed by the
+// fully-qualified names of the messages, with a leading dot. Overrides take precedence over the
+// options set in the protos.
+type overrides map[string]*messageOverride
+
+// messageOverride overrides the options of a message, and those of its fields keyed by their names.
+type messageOverride struct {
+	TableName string                    `json:"table_name"`
+	Fields    map[string]*fieldOverride `json:"fields"`
+}
+
+// fieldOverride overrides the options of a field. Unset attributes keep the options set in the proto.
+type fieldOverride struct {
+	Ignore       *bool  `json:"ignore"`
+	Name         string `json:"name"`
+	TypeOverride string `json:"type_override"`
+	Mode         string `json:"mode"`
+	Description  string `json:"description"`
+	PolicyTags   string `json:"policy_tags"`
+}
+
+// overrideModes lists the modes which the columns of fields can be overridden with.
+var overrideModes = []string{"NULLABLE", "REQUIRED"}
+
+// readOverrides reads the overrides file at the given path, which is JSON, or YAML with a .yaml or .yml
+// extension, keyed by the fully-qualified names of messages. Without a path, there are no overrides.
+func readOverrides(path string) (overrides, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	// YAML is converted to JSON, so that both are decoded the same way.
+	if ext := strings.ToLower(filepath.Ext(path)); ext == ".yaml" || ext == ".yml" {
+		if data, err = yaml.YAMLToJSON(data); err != nil {
+			return nil, fmt.Errorf("cannot parse overrides %s: %v", path, err)
+		}
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	var read map[string]*messageOverride
+	if err = decoder.Decode(&read); err != nil {
+		return nil, fmt.Errorf("cannot parse overrides %s: %v", path, err)
+	}
+	o := make(overrides, len(read))
+	for name, msg := range read {
+		if msg != nil {
+			o["."+strings.TrimPrefix(name, ".")] = msg
+		}
+	}
+	return o, nil
+}
+
+// check returns an error listing the overrides which target messages or fields missing from the
+// proto files, or which set invalid modes or types. Messages are only reported missing from the packages
+// the proto files declare, since an overrides file can be shared by protoc runs compiling parts of the
+// protos.
+func (o overrides) check(locals *Locals) error {
+	var problems []string
+	for name, msg := range o {
+		pt := locals.GetType(name)
+		if pt == nil {
+			if inDeclaredPackage(locals, name) {
+				problems = append(problems, fmt.Sprintf("message %s not found", name[1:]))
+			}
+			continue
+		}
+		for fieldName, field := range msg.Fields {
+			var fieldProto *descriptor.FieldDescriptorProto
+			for _, f := range pt.Type.GetField() {
+				if f.GetName() == fieldName {
+					fieldProto = f
+				}
+			}
+			fullName := name[1:] + "." + fieldName
+			if fieldProto != nil && field != nil && field.TypeOverride != "" && !bigQueryTypes[field.TypeOverride] {
+				problems = append(problems, fmt.Sprintf("unknown BigQuery type %q of field %s", field.TypeOverride, fullName))
+			}
+			switch {
+			case fieldProto == nil:
+				problems = append(problems, fmt.Sprintf("field %s not found", fullName))
+			case field == nil || field.Mode == "":
+			case !contains(overrideModes, field.Mode):
+				problems = append(problems, fmt.Sprintf("invalid mode %q of field %s, expected one of %s", field.Mode, fullName, strings.Join(overrideModes, ", ")))
+			case fieldProto.GetLabel() == descriptor.FieldDescriptorProto_LABEL_REPEATED:
+				problems = append(problems, fmt.Sprintf("mode of repeated field %s cannot be overridden", fullName))
+			}
+		}
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	sort.Strings(problems)
+	return fmt.Errorf("invalid overrides:\n  %s", strings.Join(problems, "\n  "))
+}
+
+// inDeclaredPackage reports whether the message with the given fully-qualified name, with a leading dot,
+// belongs to a package declared by the proto files, its own or that of an enclosing message.
+func inDeclaredPackage(locals *Locals, name string) bool {
+	scope := name[1:]
+	for {
+		idx := strings.LastIndexByte(scope, '.')
+		if idx < 0 {
+			// Only top-level names are looked up in files without a package.
+			return scope == name[1:] && locals.GetPackage("") != nil
+		}
+		if scope = scope[:idx]; locals.GetPackage(scope) != nil {
+			return true
+		}
+	}
+}
+
+// field returns the override of a field of the message with the given fully-qualified name, or nil.
+func (o overrides) field(msgName, fieldName string) *fieldOverride {
+	if msg := o[msgName]; msg != nil {
+		return msg.Fields[fieldName]
+	}
+	return nil
+}
+
+// messageOptions returns the options of a message, with the table name set by its override.
+func (o overrides) messageOptions(msg *ProtoType, opts *protos.BigQueryMessageOptions) *protos.BigQueryMessageOptions {
+	override := o[msg.Name]
+	if override == nil || override.TableName == "" {
+		return opts
+	}
+	if opts == nil {
+		opts = &protos.BigQueryMessageOptions{}
+	} else {
+		opts = proto.Clone(opts).(*protos.BigQueryMessageOptions)
+	}
+	opts.TableName = override.TableName
+	return opts
+}
+
+// fieldOptions returns the options of a field declared in the given message, with the attributes set by
+// its override. The NULLABLE mode is not an option of fields, and is applied to the column separately.
+func (o overrides) fieldOptions(msg *ProtoType, fieldProto *descriptor.FieldDescriptorProto, opts *protos.BigQueryFieldOptions) *protos.BigQueryFieldOptions {
+	override := o.field(msg.Name, fieldProto.GetName())
+	if override == nil {
+		return opts
+	}
+	if opts == nil {
+		opts = &protos.BigQueryFieldOptions{}
+	} else {
+		opts = proto.Clone(opts).(*protos.BigQueryFieldOptions)
+	}
+	if override.Ignore != nil {
+		opts.Ignore = *override.Ignore
+	}
+	if override.Name != "" {
+		opts.Name = override.Name
+	}
+	if override.TypeOverride != "" {
+		opts.TypeOverride = override.TypeOverride
+	}
+	if override.Description != "" {
+		opts.Description = override.Description
+	}
+	if override.PolicyTags != "" {
+		opts.PolicyTags = override.PolicyTags
+	}
+	if override.Mode != "" {
+		opts.Require = override.Mode == "REQUIRED"
+	}
+	return opts
+}
@@ -1,18 +1,359 @@
 package pkg
 
 import (
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
 	"strings"
+	"text/tabwriter"
+	"text/template"
+
+	"github.com/GoogleCloudPlatform/protoc-gen-bq-schema/protos"
+)
+
+const (
+	mapsRecords = "records"
+	mapsJSON    = "json"
+
+	implicitPresenceNullable = "nullable"
+	implicitPresenceRequired = "required"
+
+	formatSchema    = "schema"
+	formatDDL       = "ddl"
+	formatTable     = "table"
+	formatTerraform = "terraform"
+	formatMigration = "migration"
 )
 
-type Params map[string]string
+// oneofStrategies maps the values of the oneofs parameter to the strategies they select.
+var oneofStrategies = map[string]protos.OneofStrategy{
+	"flatten":       protos.OneofStrategy_ONEOF_FLATTEN,
+	"wrap":          protos.OneofStrategy_ONEOF_WRAP,
+	"discriminator": protos.OneofStrategy_ONEOF_DISCRIMINATOR,
+}
+
+// enumFormats maps the values of the enums parameter to the formats they select.
+var enumFormats = map[string]protos.EnumFormat{
+	"string":  protos.EnumFormat_ENUM_STRING,
+	"integer": protos.EnumFormat_ENUM_INTEGER,
+	"both":    protos.EnumFormat_ENUM_NAME_AND_NUMBER,
+}
+
+// recursionFallbacks maps the values of the recursion-fallback parameter to the fallbacks they select.
+var recursionFallbacks = map[string]protos.RecursionFallback{
+	"omit":   protos.RecursionFallback_RECURSION_OMIT,
+	"json":   protos.RecursionFallback_RECURSION_JSON,
+	"string": protos.RecursionFallback_RECURSION_STRING,
+}
+
+// Parameters holds the parameters given to the plugin with --bq-schema_opt, a comma-separated list of
+// key=value entries and flags, e.g. "format=ddl,dataset=analytics,enum-values". The parameters are
+// described in parameterDefinitions.
+type Parameters struct {
+	// Packages maps proto files to the packages their tables are generated in, as set with
+	// M<file>=<package> entries.
+	Packages map[string]string
+
+	// Include holds the patterns of the fully-qualified names of the messages generated without a
+	// table_name option, as set with include entries.
+	Include []*regexp.Regexp
+	// TableName names the tables of the included messages.
+	TableName *template.Template
+
+	SingleMessage     bool
+	RootMessage       string
+	Maps              string
+	Oneofs            protos.OneofStrategy
+	Enums             protos.EnumFormat
+	EnumValues        bool
+	RecursionDepth    int32
+	RecursionFallback protos.RecursionFallback
+	ImplicitPresence  string
+	Format            string
+	PreviousSchemas   string
+	Overrides         string
+	// OutputPath names the generated files.
+	OutputPath *template.Template
+	Project    string
+	Dataset    string
+}
+
+// parameterDefinition describes a parameter of the plugin.
+type parameterDefinition struct {
+	name string
+	// flag reports whether the parameter is a boolean flag, which is set by its name alone.
+	flag bool
+	// values lists the values accepted by parameters which only accept a fixed set of values.
+	values []string
+	usage  string
+	// set sets the parameter to a value, which is already checked to be one of values. Flags are
+	// set to "true" or "false".
+	set func(p *Parameters, value string) error
+}
+
+// parameterDefinitions lists every parameter of the plugin, and documents them for the usage of the
+// plugin. When a parameter is given several times, the last value is used, except for include.
+var parameterDefinitions = []parameterDefinition{
+	{
+		name:  "include",
+		usage: "also generate the messages without a table name whose fully-qualified names match this glob, or this regular expression after re:; repeatable",
+		set: func(p *Parameters, v string) error {
+			re, err := includePattern(v)
+			if err != nil {
+				return fmt.Errorf("invalid value %q for parameter include: %v", v, err)
+			}
+			p.Include = append(p.Include, re)
+			return nil
+		},
+	},
+	{
+		name:  "table-name",
+		usage: "template naming the tables of included messages, e.g. {{.Package | identifier}}_{{.MessageName | snake}}",
+		set: func(p *Parameters, v string) error {
+			tmpl, err := parseTemplate("table-name", v)
+			if err != nil {
+				return fmt.Errorf("invalid value %q for parameter table-name: %v", v, err)
+			}
+			p.TableName = tmpl
+			return nil
+		},
+	},
+	{
+		name:  "single-message",
+		flag:  true,
+		usage: "generate only the root message of each file, into a table named after the file unless it has a table name",
+		set: func(p *Parameters, v string) error {
+			p.SingleMessage = v == "true"
+			return nil
+		},
+	},
+	{
+		name:  "root-message",
+		usage: "with single-message, which it requires, generate the message with this name, or fully-qualified name, which every file must declare",
+		set: func(p *Parameters, v string) error {
+			p.RootMessage = v
+			return nil
+		},
+	},
+	{
+		name:   "maps",
+		values: []string{mapsRecords, mapsJSON},
+		usage:  "render map fields as repeated key/value records, or as a single JSON column",
+		set: func(p *Parameters, v string) error {
+			p.Maps = v
+			return nil
+		},
+	},
+	{
+		name:   "oneofs",
+		values: []string{"flatten", "wrap", "discriminator"},
+		usage:  "render oneofs with this strategy, unless set in the file or message options",
+		set: func(p *Parameters, v string) error {
+			p.Oneofs = oneofStrategies[v]
+			return nil
+		},
+	},
+	{
+		name:   "enums",
+		values: []string{"string", "integer", "both"},
+		usage:  "render enum fields in this format, unless set in the field options",
+		set: func(p *Parameters, v string) error {
+			p.Enums = enumFormats[v]
+			return nil
+		},
+	},
+	{
+		name:  "enum-values",
+		flag:  true,
+		usage: "append the values allowed for enum fields to their descriptions",
+		set: func(p *Parameters, v string) error {
+			p.EnumValues = v == "true"
+			return nil
+		},
+	},
+	{
+		name:  "recursion-depth",
+		usage: "expand recursive messages this many times within themselves, unless set in the message options",
+		set: func(p *Parameters, v string) error {
+			depth, err := strconv.ParseInt(v, 10, 32)
+			if err != nil || depth < 0 {
+				return fmt.Errorf("invalid value %q for parameter recursion-depth, expected a non-negative integer", v)
+			}
+			p.RecursionDepth = int32(depth)
+			return nil
+		},
+	},
+	{
+		name:   "recursion-fallback",
+		values: []string{"omit", "json", "string"},
+		usage:  "render recursive fields past the recursion depth this way, unless set in the message options",
+		set: func(p *Parameters, v string) error {
+			p.RecursionFallback = recursionFallbacks[v]
+			return nil
+		},
+	},
+	{
+		name:   "implicit-presence",
+		values: []string{implicitPresenceNullable, implicitPresenceRequired},
+		usage:  "mode of the columns of fields with implicit presence, like the scalars of proto3 which are not optional",
+		set: func(p *Parameters, v string) error {
+			p.ImplicitPresence = v
+			return nil
+		},
+	},
+	{
+		name:   "format",
+		values: []string{formatSchema, formatDDL, formatTable, formatTerraform, formatMigration},
+		usage:  "output format: JSON schema, CREATE TABLE DDL, REST table resource, Terraform resource or migration DDL",
+		set: func(p *Parameters, v string) error {
+			p.Format = v
+			return nil
+		},
+	},
+	{
+		name:  "output-path",
+		usage: "template of the paths of the generated files, e.g. schemas/{{.Dataset}}/{{.Table}}.{{.Extension}}",
+		set: func(p *Parameters, v string) error {
+			tmpl, err := parseTemplate("output-path", v)
+			if err != nil {
+				return fmt.Errorf("invalid value %q for parameter output-path: %v", v, err)
+			}
+			p.OutputPath = tmpl
+			return nil
+		},
+	},
+	{
+		name:  "previous-schemas",
+		usage: "directory holding the previously generated schemas, which tables must stay compatible with",
+		set: func(p *Parameters, v string) error {
+			p.PreviousSchemas = v
+			return nil
+		},
+	},
+	{
+		name:  "overrides",
+		usage: "JSON or YAML file overriding the options of messages and fields, for protos which cannot be annotated",
+		set: func(p *Parameters, v string) error {
+			p.Overrides = v
+			return nil
+		},
+	},
+	{
+		name:  "project",
+		usage: "project of the tables, where outputs refer to them",
+		set: func(p *Parameters, v string) error {
+			p.Project = v
+			return nil
+		},
+	},
+	{
+		name:  "dataset",
+		usage: "dataset of the tables, where outputs refer to them; required with format=terraform",
+		set: func(p *Parameters, v string) error {
+			p.Dataset = v
+			return nil
+		},
+	},
+}
+
+// ParseParameters parses the parameters given to the plugin, in the syntax of
+// CodeGeneratorRequest.parameter. It returns an error for unknown parameters and invalid values.
+func ParseParameters(parameter string) (*Parameters, error) {
+	p := &Parameters{
+		Packages:         make(map[string]string),
+		Maps:             mapsRecords,
+		ImplicitPresence: implicitPresenceNullable,
+		Format:           formatSchema,
+	}
+	for _, entry := range strings.Split(parameter, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		key, value := entry, ""
+		hasValue := false
+		if i := strings.IndexByte(entry, '='); i >= 0 {
+			key, value, hasValue = entry[:i], entry[i+1:], true
+		}
+		// Package mappings name proto files, so that mistyped keys like Maps are reported as unknown.
+		if strings.HasPrefix(key, "M") && strings.HasSuffix(key, ".proto") {
+			if !hasValue {
+				return nil, fmt.Errorf("invalid parameter %q, expected M<file>=<package>", entry)
+			}
+			p.Packages[key[1:]] = value
+			continue
+		}
+
+		def := findParameter(key)
+		if def == nil {
+			return nil, fmt.Errorf("unknown parameter %q", key)
+		}
+		if def.flag {
+			if !hasValue {
+				value = "true"
+			} else if b, err := strconv.ParseBool(value); err != nil {
+				return nil, fmt.Errorf("invalid value %q for parameter %s, expected true or false", value, key)
+			} else {
+				value = strconv.FormatBool(b)
+			}
+		} else if !hasValue {
+			return nil, fmt.Errorf("parameter %s requires a value", key)
+		}
+		if len(def.values) > 0 && !contains(def.values, value) {
+			return nil, fmt.Errorf("invalid value %q for parameter %s, expected one of %s", value, key, strings.Join(def.values, ", "))
+		}
+		if err := def.set(p, value); err != nil {
+			return nil, err
+		}
+	}
+
+	if p.Format == formatTerraform && p.Dataset == "" {
+		return nil, fmt.Errorf("parameter dataset is required with format=%s", formatTerraform)
+	}
+	if p.Format == formatMigration && p.PreviousSchemas == "" {
+		return nil, fmt.Errorf("parameter previous-schemas is required with format=%s", formatMigration)
+	}
+	if p.RootMessage != "" && !p.SingleMessage {
+		return nil, fmt.Errorf("parameter root-message requires single-message")
+	}
+	return p, nil
+}
+
+// findParameter returns the definition of the parameter with the given name, or nil.
+func findParameter(name string) *parameterDefinition {
+	for i := range parameterDefinitions {
+		if parameterDefinitions[i].name == name {
+			return &parameterDefinitions[i]
+		}
+	}
+	return nil
+}
+
+// contains reports whether values contains v.
+func contains(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
 
-func ParseRequestOptions(requestParam string) Params {
-	p := make(Params)
-	for _, s2 := range strings.Split(requestParam, ",") {
-		if s2[0] == 'M' {
-			parts := strings.Split(s2[1:], "=")
-			p[parts[0]] = parts[1]
+// PrintParameters writes the documentation of the parameters of the plugin to w.
+func PrintParameters(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "  M<file>.proto=<package>\tgenerate the tables of a proto file in another package")
+	for _, def := range parameterDefinitions {
+		name := def.name
+		switch {
+		case def.flag:
+		case len(def.values) > 0:
+			name += "=" + strings.Join(def.values, "|")
+		default:
+			name += "=<value>"
 		}
+		fmt.Fprintf(tw, "  %s\t%s\n", name, def.usage)
 	}
-	return p
+	return tw.Flush()
 }
@@ -0,0 +1,117 @@
+package pkg
+
+import (
+	"fmt"
+	"strings"
+)
+
+var (
+	partitioningTypes = map[string]bool{"DAY": true, "HOUR": true, "MONTH": true, "YEAR": true}
+
+	// timePartitioningColumnTypes maps the types of the columns a table can be partitioned by to
+	// the granularities they accept.
+	timePartitioningColumnTypes = map[string]map[string]bool{
+		"TIMESTAMP": partitioningTypes,
+		"DATETIME":  partitioningTypes,
+		"DATE":      {"DAY": true, "MONTH": true, "YEAR": true},
+	}
+
+	clusteringColumnTypes = map[string]bool{
+		"STRING": true, "INT64": true, "NUMERIC": true, "BIGNUMERIC": true, "BOOL": true,
+		"DATE": true, "DATETIME": true, "TIMESTAMP": true, "GEOGRAPHY": true,
+	}
+)
+
+// maxClusteringFields is the maximum number of columns a table can be clustered by.
+const maxClusteringFields = 4
+
+// timePartitioningType returns the granularity of the time partitioning of the table.
+func timePartitioningType(t *Table) string {
+	if tp := t.Options.GetTimePartitioning(); tp.GetType() != "" {
+		return strings.ToUpper(tp.GetType())
+	}
+	return "DAY"
+}
+
+// validatePartitioning checks that the partitioning and clustering options of a table refer to
+// top-level columns of its schema, with types BigQuery accepts for them.
+func validatePartitioning(table *Table) error {
+	tp := table.Options.GetTimePartitioning()
+	rp := table.Options.GetRangePartitioning()
+	if tp != nil && rp != nil {
+		return fmt.Errorf("table %s cannot have both time_partitioning and range_partitioning", table.Name)
+	}
+
+	if tp != nil {
+		granularity := timePartitioningType(table)
+		if !partitioningTypes[granularity] {
+			return fmt.Errorf("invalid time_partitioning type %q of table %s, expected one of DAY, HOUR, MONTH, YEAR", tp.GetType(), table.Name)
+		}
+		if tp.GetExpirationMs() < 0 {
+			return fmt.Errorf("invalid time_partitioning expiration_ms %d of table %s", tp.GetExpirationMs(), table.Name)
+		}
+		if tp.GetField() != "" {
+			column, err := partitioningColumn(table, "time_partitioning", tp.GetField())
+			if err != nil {
+				return err
+			}
+			granularities, ok := timePartitioningColumnTypes[sqlType(column)]
+			if !ok {
+				return fmt.Errorf("time_partitioning field %s of table %s is a %s column, expected TIMESTAMP, DATE or DATETIME", tp.GetField(), table.Name, column.Type)
+			}
+			if !granularities[granularity] {
+				return fmt.Errorf("time_partitioning field %s of table %s is a %s column, which cannot be partitioned by %s", tp.GetField(), table.Name, column.Type, granularity)
+			}
+		}
+	}
+
+	if rp != nil {
+		column, err := partitioningColumn(table, "range_partitioning", rp.GetField())
+		if err != nil {
+			return err
+		}
+		if sqlType(column) != "INT64" {
+			return fmt.Errorf("range_partitioning field %s of table %s is a %s column, expected INTEGER", rp.GetField(), table.Name, column.Type)
+		}
+		if rp.GetInterval() <= 0 || rp.GetEnd() <= rp.GetStart() {
+			return fmt.Errorf("invalid range_partitioning of table %s: expected start < end and a positive interval", table.Name)
+		}
+	}
+
+	if table.Options.GetRequirePartitionFilter() && tp == nil && rp == nil {
+		return fmt.Errorf("table %s requires a partition filter but is not partitioned", table.Name)
+	}
+
+	fields := table.Options.GetClusteringFields()
+	if len(fields) > maxClusteringFields {
+		return fmt.Errorf("table %s is clustered by %d fields, at most %d are allowed", table.Name, len(fields), maxClusteringFields)
+	}
+	for _, name := range fields {
+		column, err := partitioningColumn(table, "clustering", name)
+		if err != nil {
+			return err
+		}
+		if !clusteringColumnTypes[sqlType(column)] {
+			return fmt.Errorf("clustering field %s of table %s is a %s column, which cannot be clustered by", name, table.Name, column.Type)
+		}
+	}
+	return nil
+}
+
+// partitioningColumn returns the top-level column of a table referred to by a partitioning or
+// clustering option. Such columns cannot be repeated.
+func partitioningColumn(table *Table, option, name string) (*Field, error) {
+	if name == "" {
+		return nil, fmt.Errorf("%s of table %s has no field", option, table.Name)
+	}
+	for _, f := range table.Schema {
+		if !strings.EqualFold(f.Name, name) {
+			continue
+		}
+		if f.Mode == "REPEATED" {
+			return nil, fmt.Errorf("%s field %s of table %s cannot be REPEATED", option, name, table.Name)
+		}
+		return f, nil
+	}
+	return nil, fmt.Errorf("%s field %s of table %s is not a top-level column of the table", option, name, table.Name)
+}
@@ -0,0 +1,92 @@
+package pkg
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"strings"
+	"text/template"
+)
+
+// defaultOutputPath lays out the generated files by package, unless set with the output-path parameter.
+var defaultOutputPath = template.Must(parseTemplate("output-path", "{{with .PackageDir}}{{.}}/{{end}}{{.Table}}.{{.Extension}}"))
+
+// outputPathData is the data output path templates are executed with.
+type outputPathData struct {
+	// Package is the package the table is generated in, e.g. "foo.events", as mapped with M parameters.
+	Package string
+	// PackageDir is the package of the message with dots replaced by slashes, e.g. "foo/events".
+	PackageDir string
+	// FileDir is the directory of the proto file declaring the message, e.g. "protos/foo", or "" at the root.
+	FileDir string
+	// dataset is the dataset given with the dataset parameter, which templates get with Dataset.
+	dataset string
+	// Table is the name of the table.
+	Table string
+	// MessageName is the name of the message, e.g. "PageView".
+	MessageName string
+	// Extension is the extension of the output format, e.g. "schema" or "sql".
+	Extension string
+}
+
+// Dataset returns the dataset given with the dataset parameter, failing the template if it is not set.
+func (d outputPathData) Dataset() (string, error) {
+	if d.dataset == "" {
+		return "", fmt.Errorf("parameter dataset is not set")
+	}
+	return d.dataset, nil
+}
+
+// outputPath returns the path of the file generated in the given format extension for the table of a
+// message of the given package, following the output-path template.
+func (g *generation) outputPath(pkgName string, msg *ProtoType, table *Table, extension string) (string, error) {
+	tmpl := g.params.OutputPath
+	if tmpl == nil {
+		tmpl = defaultOutputPath
+	}
+	fileDir := path.Dir(msg.File.GetName())
+	if fileDir == "." {
+		fileDir = ""
+	}
+	var name bytes.Buffer
+	if err := tmpl.Execute(&name, outputPathData{
+		Package:     pkgName,
+		PackageDir:  strings.Replace(pkgName, ".", "/", -1),
+		FileDir:     fileDir,
+		dataset:     g.params.Dataset,
+		Table:       table.Name,
+		MessageName: msg.Type.GetName(),
+		Extension:   extension,
+	}); err != nil {
+		return "", fmt.Errorf("cannot name the output of table %s: %v", table.Name, err)
+	}
+
+	// protoc expects relative and normalized paths of files.
+	if strings.HasPrefix(name.String(), "/") {
+		return "", fmt.Errorf("output %s of table %s is an absolute path", name.String(), table.Name)
+	}
+	for _, element := range strings.Split(name.String(), "/") {
+		switch element {
+		case "":
+			return "", fmt.Errorf("output %q of table %s has an empty path element", name.String(), table.Name)
+		case ".":
+			return "", fmt.Errorf("output %s of table %s is not normalized", name.String(), table.Name)
+		case "..":
+			return "", fmt.Errorf("output %s of table %s is outside of the output directory", name.String(), table.Name)
+		}
+	}
+	return name.String(), nil
+}
+
+// claimOutput records that a message generates the file at the given path, and returns an error if
+// another message already generates it.
+func (g *generation) claimOutput(path string, msg *ProtoType) error {
+	if other, ok := g.outputs[path]; ok && other == msg.Name {
+		return fmt.Errorf("message %s generates several outputs at %s", strings.TrimPrefix(msg.Name, "."), path)
+	} else if ok {
+		return fmt.Errorf("output %s of message %s collides with that of message %s",
+			path, strings.TrimPrefix(msg.Name, "."), strings.TrimPrefix(other, "."))
+	}
+	g.outputs[path] = msg.Name
+	return nil
+}
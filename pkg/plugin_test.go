@@ -15,15 +15,27 @@
 package pkg
 
 import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
 	"testing"
 
 	plugin "github.com/golang/protobuf/protoc-gen-go/plugin"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	descriptor "google.golang.org/protobuf/types/descriptorpb"
 )
 
-// schema is an internal representation of generated BigQuery schema
-type schema []map[string]interface{}
+var update = flag.Bool("update", false, "regenerate the golden files under examples/")
 
-func joinNames(targets map[string]*schema) (result string) {
+func joinNames(targets map[string]string) (result string) {
 	sep := ""
 	for name := range targets {
 		result += sep + name
@@ -32,8 +44,66 @@ func joinNames(targets map[string]*schema) (result string) {
 	return
 }
 
+// equalOutputs reports whether two generated files are equal, comparing JSON semantically and other
+// formats as text, ignoring leading and trailing white space.
+func equalOutputs(expected, actual string) bool {
+	var expectedJSON, actualJSON interface{}
+	if json.Unmarshal([]byte(expected), &expectedJSON) == nil && json.Unmarshal([]byte(actual), &actualJSON) == nil {
+		return reflect.DeepEqual(expectedJSON, actualJSON)
+	}
+	return strings.TrimSpace(expected) == strings.TrimSpace(actual)
+}
+
+// generate parses a CodeGeneratorRequest in the text format, and runs the generator on it.
+func generate(t *testing.T, input string, extras ...func(request *plugin.CodeGeneratorRequest)) *plugin.CodeGeneratorResponse {
+	t.Helper()
+	req := &plugin.CodeGeneratorRequest{}
+	if err := prototext.Unmarshal([]byte(input), req); err != nil {
+		t.Fatal("Failed to parse test input: ", err)
+	}
+	for _, extra := range extras {
+		extra(req)
+	}
+	res, err := NewGenerator().Generate(req)
+	if err != nil {
+		t.Fatal("Failed to generate: ", err)
+	}
+	return res
+}
+
 func testConvert(t *testing.T, input string, expectedOutputs map[string]string, extras ...func(request *plugin.CodeGeneratorRequest)) {
-	return
+	t.Helper()
+	res := generate(t, input, extras...)
+	if res.Error != nil {
+		t.Fatal("Failed to convert: ", res.GetError())
+	}
+
+	actualOutputs := make(map[string]string)
+	for _, file := range res.GetFile() {
+		actualOutputs[file.GetName()] = file.GetContent()
+	}
+	for name, expected := range expectedOutputs {
+		actual, ok := actualOutputs[name]
+		if !ok {
+			t.Errorf("Expected %s to be generated, got %s", name, joinNames(actualOutputs))
+			continue
+		}
+		if !equalOutputs(expected, actual) {
+			t.Errorf("Expected %s to be equal to\n%s\ngot\n%s", name, expected, actual)
+		}
+		delete(actualOutputs, name)
+	}
+	if len(actualOutputs) > 0 {
+		t.Errorf("Unexpected files generated: %s", joinNames(actualOutputs))
+	}
+}
+
+// testConvertError tests that converting the request fails with the expected error.
+func testConvertError(t *testing.T, input string, expectedError string, extras ...func(request *plugin.CodeGeneratorRequest)) {
+	t.Helper()
+	if res := generate(t, input, extras...); res.GetError() != expectedError {
+		t.Errorf("Expected conversion to fail with\n%s\ngot\n%s", expectedError, res.GetError())
+	}
 }
 
 // TestSimple tries a simple code generator request.
@@ -41,18 +111,18 @@ func TestSimple(t *testing.T) {
 	testConvert(t, `
 			file_to_generate: "foo.proto"
 			proto_file <
-				Name: "foo.proto"
+				name: "foo.proto"
 				package: "example_package.nested"
 				message_type <
-					Name: "FooProto"
-					field < Name: "i1" number: 1 type: TYPE_INT32 label: LABEL_OPTIONAL >
+					name: "FooProto"
+					field < name: "i1" number: 1 type: TYPE_INT32 label: LABEL_OPTIONAL >
 					options < [gen_bq_schema.bigquery_opts] <table_name: "foo_table"> >
 				>
 			>
 		`,
 		map[string]string{
 			"example_package/nested/foo_table.schema": `[
-				{ "Name": "i1", "type": "INTEGER", "mode": "NULLABLE" }
+				{ "name": "i1", "type": "INTEGER", "mode": "NULLABLE" }
 			]`,
 		})
 }
@@ -62,26 +132,26 @@ func TestIgnoreNonTargetMessage(t *testing.T) {
 	testConvert(t, `
 			file_to_generate: "foo.proto"
 			proto_file <
-				Name: "foo.proto"
+				name: "foo.proto"
 				package: "example_package.nested"
 				message_type <
-					Name: "FooProto"
-					field < Name: "i1" number: 1 type: TYPE_INT32 label: LABEL_OPTIONAL >
+					name: "FooProto"
+					field < name: "i1" number: 1 type: TYPE_INT32 label: LABEL_OPTIONAL >
 				>
 				message_type <
-					Name: "BarProto"
-					field < Name: "i1" number: 1 type: TYPE_INT32 label: LABEL_OPTIONAL >
+					name: "BarProto"
+					field < name: "i1" number: 1 type: TYPE_INT32 label: LABEL_OPTIONAL >
 					options < [gen_bq_schema.bigquery_opts] <table_name: "bar_table"> >
 				>
 				message_type <
-					Name: "BazProto"
-					field < Name: "i1" number: 1 type: TYPE_INT32 label: LABEL_OPTIONAL >
+					name: "BazProto"
+					field < name: "i1" number: 1 type: TYPE_INT32 label: LABEL_OPTIONAL >
 				>
 			>
 		`,
 		map[string]string{
 			"example_package/nested/bar_table.schema": `[
-				{ "Name": "i1", "type": "INTEGER", "mode": "NULLABLE" }
+				{ "name": "i1", "type": "INTEGER", "mode": "NULLABLE" }
 			]`,
 		})
 }
@@ -91,27 +161,27 @@ func TestIgnoreNonTargetFile(t *testing.T) {
 	testConvert(t, `
 			file_to_generate: "foo.proto"
 			proto_file <
-				Name: "foo.proto"
+				name: "foo.proto"
 				package: "example_package.nested"
 				message_type <
-					Name: "FooProto"
-					field < Name: "i1" number: 1 type: TYPE_INT32 label: LABEL_OPTIONAL >
+					name: "FooProto"
+					field < name: "i1" number: 1 type: TYPE_INT32 label: LABEL_OPTIONAL >
 					options < [gen_bq_schema.bigquery_opts] <table_name: "foo_table"> >
 				>
 			>
 			proto_file <
-				Name: "bar.proto"
+				name: "bar.proto"
 				package: "example_package.nested"
 				message_type <
-					Name: "BarProto"
-					field < Name: "i1" number: 1 type: TYPE_INT32 label: LABEL_OPTIONAL >
+					name: "BarProto"
+					field < name: "i1" number: 1 type: TYPE_INT32 label: LABEL_OPTIONAL >
 					options < [gen_bq_schema.bigquery_opts] <table_name: "bar_table"> >
 				>
 			>
 		`,
 		map[string]string{
 			"example_package/nested/foo_table.schema": `[
-				{ "Name": "i1", "type": "INTEGER", "mode": "NULLABLE" }
+				{ "name": "i1", "type": "INTEGER", "mode": "NULLABLE" }
 			]`,
 		})
 }
@@ -122,33 +192,33 @@ func TestStopsAtRecursiveMessage(t *testing.T) {
 	testConvert(t, `
 			file_to_generate: "foo.proto"
 			proto_file <
-				Name: "foo.proto"
+				name: "foo.proto"
 				package: "example_package.recursive"
 				message_type <
-					Name: "FooProto"
-					field < Name: "i1" number: 1 type: TYPE_INT32 label: LABEL_OPTIONAL >
+					name: "FooProto"
+					field < name: "i1" number: 1 type: TYPE_INT32 label: LABEL_OPTIONAL >
 					field <
-						Name: "bar" number: 2 type: TYPE_MESSAGE label: LABEL_OPTIONAL
+						name: "bar" number: 2 type: TYPE_MESSAGE label: LABEL_OPTIONAL
 						type_name: "BarProto" >
 					options < [gen_bq_schema.bigquery_opts] <table_name: "foo_table"> >
 				>
 				message_type <
-					Name: "BarProto"
-					field < Name: "i2" number: 1 type: TYPE_INT32 label: LABEL_OPTIONAL >
+					name: "BarProto"
+					field < name: "i2" number: 1 type: TYPE_INT32 label: LABEL_OPTIONAL >
 					field <
-						Name: "foo" number: 2 type: TYPE_MESSAGE label: LABEL_OPTIONAL
+						name: "foo" number: 2 type: TYPE_MESSAGE label: LABEL_OPTIONAL
 						type_name: "FooProto" >
 				>
 			>
 		`,
 		map[string]string{
 			"example_package/recursive/foo_table.schema": `[
-				{ "Name": "i1", "type": "INTEGER", "mode": "NULLABLE" },
+				{ "name": "i1", "type": "INTEGER", "mode": "NULLABLE" },
 				{
-					"Name": "bar",
+					"name": "bar",
 					"type": "RECORD",
 					"mode": "NULLABLE",
-					"fields": [{ "Name": "i2", "type": "INTEGER", "mode": "NULLABLE" }]
+					"fields": [{ "name": "i2", "type": "INTEGER", "mode": "NULLABLE" }]
 				}
 			]`,
 		})
@@ -159,136 +229,137 @@ func TestTypes(t *testing.T) {
 	testConvert(t, `
 			file_to_generate: "foo.proto"
 			proto_file <
-				Name: "foo.proto"
+				name: "foo.proto"
 				package: "example_package.nested"
 				message_type <
-					Name: "FooProto"
-					field < Name: "i32" number: 1 type: TYPE_INT32 label: LABEL_OPTIONAL >
-					field < Name: "i64" number: 2 type: TYPE_INT64 label: LABEL_OPTIONAL >
-					field < Name: "ui32" number: 3 type: TYPE_UINT32 label: LABEL_OPTIONAL >
-					field < Name: "ui64" number: 4 type: TYPE_UINT64 label: LABEL_OPTIONAL >
-					field < Name: "si32" number: 5 type: TYPE_SINT32 label: LABEL_OPTIONAL >
-					field < Name: "si64" number: 6 type: TYPE_SINT64 label: LABEL_OPTIONAL >
-					field < Name: "ufi32" number: 7 type: TYPE_FIXED32 label: LABEL_OPTIONAL >
-					field < Name: "ufi64" number: 8 type: TYPE_FIXED64 label: LABEL_OPTIONAL >
-					field < Name: "sfi32" number: 9 type: TYPE_SFIXED32 label: LABEL_OPTIONAL >
-					field < Name: "sfi64" number: 10 type: TYPE_SFIXED64 label: LABEL_OPTIONAL >
-					field < Name: "d" number: 11 type: TYPE_DOUBLE label: LABEL_OPTIONAL >
-					field < Name: "f" number: 12 type: TYPE_FLOAT label: LABEL_OPTIONAL >
-					field < Name: "bool" number: 16 type: TYPE_BOOL label: LABEL_OPTIONAL >
-					field < Name: "str" number: 13 type: TYPE_STRING label: LABEL_OPTIONAL >
-					field < Name: "bytes" number: 14 type: TYPE_BYTES label: LABEL_OPTIONAL >
-					field <
-						Name: "enum1" number: 15 type: TYPE_ENUM label: LABEL_OPTIONAL
+					name: "FooProto"
+					field < name: "i32" number: 1 type: TYPE_INT32 label: LABEL_OPTIONAL >
+					field < name: "i64" number: 2 type: TYPE_INT64 label: LABEL_OPTIONAL >
+					field < name: "ui32" number: 3 type: TYPE_UINT32 label: LABEL_OPTIONAL >
+					field < name: "ui64" number: 4 type: TYPE_UINT64 label: LABEL_OPTIONAL >
+					field < name: "si32" number: 5 type: TYPE_SINT32 label: LABEL_OPTIONAL >
+					field < name: "si64" number: 6 type: TYPE_SINT64 label: LABEL_OPTIONAL >
+					field < name: "ufi32" number: 7 type: TYPE_FIXED32 label: LABEL_OPTIONAL >
+					field < name: "ufi64" number: 8 type: TYPE_FIXED64 label: LABEL_OPTIONAL >
+					field < name: "sfi32" number: 9 type: TYPE_SFIXED32 label: LABEL_OPTIONAL >
+					field < name: "sfi64" number: 10 type: TYPE_SFIXED64 label: LABEL_OPTIONAL >
+					field < name: "d" number: 11 type: TYPE_DOUBLE label: LABEL_OPTIONAL >
+					field < name: "f" number: 12 type: TYPE_FLOAT label: LABEL_OPTIONAL >
+					field < name: "bool" number: 16 type: TYPE_BOOL label: LABEL_OPTIONAL >
+					field < name: "str" number: 13 type: TYPE_STRING label: LABEL_OPTIONAL >
+					field < name: "bytes" number: 14 type: TYPE_BYTES label: LABEL_OPTIONAL >
+					field <
+						name: "enum1" number: 15 type: TYPE_ENUM label: LABEL_OPTIONAL
 						type_name: ".example_package.nested.FooProto.Enum1"
 					>
 					field <
-						Name: "enum2" number: 16 type: TYPE_ENUM label: LABEL_OPTIONAL
+						name: "enum2" number: 16 type: TYPE_ENUM label: LABEL_OPTIONAL
 						type_name: "FooProto.Enum1"
 					>
 					field <
-						Name: "grp1" number: 17 type: TYPE_GROUP label: LABEL_OPTIONAL
+						name: "grp1" number: 17 type: TYPE_GROUP label: LABEL_OPTIONAL
 						type_name: ".example_package.nested.FooProto.Group1"
 					>
 					field <
-						Name: "grp2" number: 18 type: TYPE_GROUP label: LABEL_OPTIONAL
+						name: "grp2" number: 18 type: TYPE_GROUP label: LABEL_OPTIONAL
 						type_name: "FooProto.Group1"
 					>
 					field <
-						Name: "msg1" number: 19 type: TYPE_MESSAGE label: LABEL_OPTIONAL
+						name: "msg1" number: 19 type: TYPE_MESSAGE label: LABEL_OPTIONAL
 						type_name: ".example_package.nested.FooProto.Nested1"
 					>
 					field <
-						Name: "msg2" number: 20 type: TYPE_MESSAGE label: LABEL_OPTIONAL
+						name: "msg2" number: 20 type: TYPE_MESSAGE label: LABEL_OPTIONAL
 						type_name: "FooProto.Nested1"
 					>
 					field <
-						Name: "msg3" number: 21 type: TYPE_MESSAGE label: LABEL_OPTIONAL
+						name: "msg3" number: 21 type: TYPE_MESSAGE label: LABEL_OPTIONAL
 						type_name: ".example_package.nested2.BarProto"
 					>
 					field <
-						Name: "msg4" number: 22 type: TYPE_MESSAGE label: LABEL_OPTIONAL
+						name: "msg4" number: 22 type: TYPE_MESSAGE label: LABEL_OPTIONAL
 						type_name: "nested2.BarProto"
 					>
 					field <
-						Name: "msg2" number: 23 type: TYPE_MESSAGE label: LABEL_OPTIONAL
+						name: "msg2" number: 23 type: TYPE_MESSAGE label: LABEL_OPTIONAL
 						type_name: "FooProto.EmptyNested1"
+						options < [gen_bq_schema.bigquery] < ignore: true > >
 					>
 					nested_type <
-						Name: "Group1"
-						field < Name: "i1" number: 1 type: TYPE_INT32 label: LABEL_OPTIONAL >
+						name: "Group1"
+						field < name: "i1" number: 1 type: TYPE_INT32 label: LABEL_OPTIONAL >
 					>
 					nested_type <
-						Name: "Nested1"
-						field < Name: "i1" number: 1 type: TYPE_INT32 label: LABEL_OPTIONAL >
+						name: "Nested1"
+						field < name: "i1" number: 1 type: TYPE_INT32 label: LABEL_OPTIONAL >
 					>
 					nested_type <
-						Name: "EmptyNested1"
+						name: "EmptyNested1"
 					>
-					enum_type < Name: "Enum1" value < Name: "E1" number: 1 > value < Name: "E2" number: 2 > >
+					enum_type < name: "Enum1" value < name: "E1" number: 1 > value < name: "E2" number: 2 > >
 					options < [gen_bq_schema.bigquery_opts] <table_name: "foo_table"> >
 				>
 			>
 			proto_file <
-				Name: "bar.proto"
+				name: "bar.proto"
 				package: "example_package.nested2"
 				message_type <
-					Name: "BarProto"
-					field < Name: "i1" number: 1 type: TYPE_INT32 label: LABEL_OPTIONAL >
-					field < Name: "i2" number: 2 type: TYPE_INT32 label: LABEL_OPTIONAL >
-					field < Name: "i3" number: 3 type: TYPE_INT32 label: LABEL_OPTIONAL >
+					name: "BarProto"
+					field < name: "i1" number: 1 type: TYPE_INT32 label: LABEL_OPTIONAL >
+					field < name: "i2" number: 2 type: TYPE_INT32 label: LABEL_OPTIONAL >
+					field < name: "i3" number: 3 type: TYPE_INT32 label: LABEL_OPTIONAL >
 				>
 			>
 		`,
 		map[string]string{
 			"example_package/nested/foo_table.schema": `[
-				{ "Name": "i32", "type": "INTEGER", "mode": "NULLABLE" },
-				{ "Name": "i64", "type": "INTEGER", "mode": "NULLABLE" },
-				{ "Name": "ui32", "type": "INTEGER", "mode": "NULLABLE" },
-				{ "Name": "ui64", "type": "INTEGER", "mode": "NULLABLE" },
-				{ "Name": "si32", "type": "INTEGER", "mode": "NULLABLE" },
-				{ "Name": "si64", "type": "INTEGER", "mode": "NULLABLE" },
-				{ "Name": "ufi32", "type": "INTEGER", "mode": "NULLABLE" },
-				{ "Name": "ufi64", "type": "INTEGER", "mode": "NULLABLE" },
-				{ "Name": "sfi32", "type": "INTEGER", "mode": "NULLABLE" },
-				{ "Name": "sfi64", "type": "INTEGER", "mode": "NULLABLE" },
-				{ "Name": "d", "type": "FLOAT", "mode": "NULLABLE" },
-				{ "Name": "f", "type": "FLOAT", "mode": "NULLABLE" },
-				{ "Name": "bool", "type": "BOOLEAN", "mode": "NULLABLE" },
-				{ "Name": "str", "type": "STRING", "mode": "NULLABLE" },
-				{ "Name": "bytes", "type": "BYTES", "mode": "NULLABLE" },
-				{ "Name": "enum1", "type": "STRING", "mode": "NULLABLE" },
-				{ "Name": "enum2", "type": "STRING", "mode": "NULLABLE" },
-				{
-					"Name": "grp1", "type": "RECORD", "mode": "NULLABLE",
-					"fields": [{ "Name": "i1", "type": "INTEGER", "mode": "NULLABLE" }]
+				{ "name": "i32", "type": "INTEGER", "mode": "NULLABLE" },
+				{ "name": "i64", "type": "INTEGER", "mode": "NULLABLE" },
+				{ "name": "ui32", "type": "INTEGER", "mode": "NULLABLE" },
+				{ "name": "ui64", "type": "INTEGER", "mode": "NULLABLE" },
+				{ "name": "si32", "type": "INTEGER", "mode": "NULLABLE" },
+				{ "name": "si64", "type": "INTEGER", "mode": "NULLABLE" },
+				{ "name": "ufi32", "type": "INTEGER", "mode": "NULLABLE" },
+				{ "name": "ufi64", "type": "INTEGER", "mode": "NULLABLE" },
+				{ "name": "sfi32", "type": "INTEGER", "mode": "NULLABLE" },
+				{ "name": "sfi64", "type": "INTEGER", "mode": "NULLABLE" },
+				{ "name": "d", "type": "FLOAT", "mode": "NULLABLE" },
+				{ "name": "f", "type": "FLOAT", "mode": "NULLABLE" },
+				{ "name": "bool", "type": "BOOLEAN", "mode": "NULLABLE" },
+				{ "name": "str", "type": "STRING", "mode": "NULLABLE" },
+				{ "name": "bytes", "type": "BYTES", "mode": "NULLABLE" },
+				{ "name": "enum1", "type": "STRING", "mode": "NULLABLE" },
+				{ "name": "enum2", "type": "STRING", "mode": "NULLABLE" },
+				{
+					"name": "grp1", "type": "RECORD", "mode": "NULLABLE",
+					"fields": [{ "name": "i1", "type": "INTEGER", "mode": "NULLABLE" }]
 				},
 				{
-					"Name": "grp2", "type": "RECORD", "mode": "NULLABLE",
-					"fields": [{ "Name": "i1", "type": "INTEGER", "mode": "NULLABLE" }]
+					"name": "grp2", "type": "RECORD", "mode": "NULLABLE",
+					"fields": [{ "name": "i1", "type": "INTEGER", "mode": "NULLABLE" }]
 				},
 				{
-					"Name": "msg1", "type": "RECORD", "mode": "NULLABLE",
-					"fields": [{ "Name": "i1", "type": "INTEGER", "mode": "NULLABLE" }]
+					"name": "msg1", "type": "RECORD", "mode": "NULLABLE",
+					"fields": [{ "name": "i1", "type": "INTEGER", "mode": "NULLABLE" }]
 				},
 				{
-					"Name": "msg2", "type": "RECORD", "mode": "NULLABLE",
-					"fields": [{ "Name": "i1", "type": "INTEGER", "mode": "NULLABLE" }]
+					"name": "msg2", "type": "RECORD", "mode": "NULLABLE",
+					"fields": [{ "name": "i1", "type": "INTEGER", "mode": "NULLABLE" }]
 				},
 				{
-					"Name": "msg3", "type": "RECORD", "mode": "NULLABLE",
+					"name": "msg3", "type": "RECORD", "mode": "NULLABLE",
 					"fields": [
-						{ "Name": "i1", "type": "INTEGER", "mode": "NULLABLE" },
-						{ "Name": "i2", "type": "INTEGER", "mode": "NULLABLE" },
-						{ "Name": "i3", "type": "INTEGER", "mode": "NULLABLE" }
+						{ "name": "i1", "type": "INTEGER", "mode": "NULLABLE" },
+						{ "name": "i2", "type": "INTEGER", "mode": "NULLABLE" },
+						{ "name": "i3", "type": "INTEGER", "mode": "NULLABLE" }
 					]
 				},
 				{
-					"Name": "msg4", "type": "RECORD", "mode": "NULLABLE",
+					"name": "msg4", "type": "RECORD", "mode": "NULLABLE",
 					"fields": [
-						{ "Name": "i1", "type": "INTEGER", "mode": "NULLABLE" },
-						{ "Name": "i2", "type": "INTEGER", "mode": "NULLABLE" },
-						{ "Name": "i3", "type": "INTEGER", "mode": "NULLABLE" }
+						{ "name": "i1", "type": "INTEGER", "mode": "NULLABLE" },
+						{ "name": "i2", "type": "INTEGER", "mode": "NULLABLE" },
+						{ "name": "i3", "type": "INTEGER", "mode": "NULLABLE" }
 					]
 				}
 			]`,
@@ -301,52 +372,52 @@ func TestWellKnownTypes(t *testing.T) {
 	testConvert(t, `
 			file_to_generate: "foo.proto"
 			proto_file <
-				Name: "foo.proto"
+				name: "foo.proto"
 				package: "example_package"
 				message_type <
-					Name: "FooProto"
+					name: "FooProto"
 					field <
-						Name: "i32" number: 1 type: TYPE_MESSAGE label: LABEL_OPTIONAL
+						name: "i32" number: 1 type: TYPE_MESSAGE label: LABEL_OPTIONAL
 						type_name: ".google.protobuf.Int32Value"
 					>
 					field <
-						Name: "i64" number: 2 type: TYPE_MESSAGE label: LABEL_OPTIONAL
+						name: "i64" number: 2 type: TYPE_MESSAGE label: LABEL_OPTIONAL
 						type_name: ".google.protobuf.Int64Value"
 					>
 					field <
-						Name: "ui32" number: 3 type: TYPE_MESSAGE label: LABEL_OPTIONAL
+						name: "ui32" number: 3 type: TYPE_MESSAGE label: LABEL_OPTIONAL
 						type_name: ".google.protobuf.UInt32Value"
 					>
 					field <
-						Name: "ui64" number: 4 type: TYPE_MESSAGE label: LABEL_OPTIONAL
+						name: "ui64" number: 4 type: TYPE_MESSAGE label: LABEL_OPTIONAL
 						type_name: ".google.protobuf.UInt64Value"
 					>
 					field <
-						Name: "d" number: 5 type: TYPE_MESSAGE label: LABEL_OPTIONAL
+						name: "d" number: 5 type: TYPE_MESSAGE label: LABEL_OPTIONAL
 						type_name: ".google.protobuf.DoubleValue"
 					>
 					field <
-						Name: "f" number: 6 type: TYPE_MESSAGE label: LABEL_OPTIONAL
+						name: "f" number: 6 type: TYPE_MESSAGE label: LABEL_OPTIONAL
 						type_name: ".google.protobuf.FloatValue"
 					>
 					field <
-						Name: "bool" number: 7 type: TYPE_MESSAGE label: LABEL_OPTIONAL
+						name: "bool" number: 7 type: TYPE_MESSAGE label: LABEL_OPTIONAL
 						type_name: ".google.protobuf.BoolValue"
 					>
 					field <
-						Name: "str" number: 8 type: TYPE_MESSAGE label: LABEL_OPTIONAL
+						name: "str" number: 8 type: TYPE_MESSAGE label: LABEL_OPTIONAL
 						type_name: ".google.protobuf.StringValue"
 					>
 					field <
-						Name: "bytes" number: 9 type: TYPE_MESSAGE label: LABEL_OPTIONAL
+						name: "bytes" number: 9 type: TYPE_MESSAGE label: LABEL_OPTIONAL
 						type_name: ".google.protobuf.BytesValue"
 					>
 					field <
-						Name: "du" number: 10 type: TYPE_MESSAGE label: LABEL_OPTIONAL
+						name: "du" number: 10 type: TYPE_MESSAGE label: LABEL_OPTIONAL
 						type_name: ".google.protobuf.Duration"
 					>
 					field <
-						Name: "t" number: 11 type: TYPE_MESSAGE label: LABEL_OPTIONAL
+						name: "t" number: 11 type: TYPE_MESSAGE label: LABEL_OPTIONAL
 						type_name: ".google.protobuf.Timestamp"
 					>
 					options < [gen_bq_schema.bigquery_opts] <table_name: "foo_table"> >
@@ -355,17 +426,17 @@ func TestWellKnownTypes(t *testing.T) {
 		`,
 		map[string]string{
 			"example_package/foo_table.schema": `[
-				{ "Name": "i32", "type": "INTEGER", "mode": "NULLABLE" },
-				{ "Name": "i64", "type": "INTEGER", "mode": "NULLABLE" },
-				{ "Name": "ui32", "type": "INTEGER", "mode": "NULLABLE" },
-				{ "Name": "ui64", "type": "INTEGER", "mode": "NULLABLE" },
-				{ "Name": "d", "type": "FLOAT", "mode": "NULLABLE" },
-				{ "Name": "f", "type": "FLOAT", "mode": "NULLABLE" },
-				{ "Name": "bool", "type": "BOOLEAN", "mode": "NULLABLE" },
-				{ "Name": "str", "type": "STRING", "mode": "NULLABLE" },
-				{ "Name": "bytes", "type": "BYTES", "mode": "NULLABLE" },
-				{ "Name": "du", "type": "STRING", "mode": "NULLABLE" },
-				{ "Name": "t", "type": "TIMESTAMP", "mode": "NULLABLE" }
+				{ "name": "i32", "type": "INTEGER", "mode": "NULLABLE" },
+				{ "name": "i64", "type": "INTEGER", "mode": "NULLABLE" },
+				{ "name": "ui32", "type": "INTEGER", "mode": "NULLABLE" },
+				{ "name": "ui64", "type": "INTEGER", "mode": "NULLABLE" },
+				{ "name": "d", "type": "FLOAT", "mode": "NULLABLE" },
+				{ "name": "f", "type": "FLOAT", "mode": "NULLABLE" },
+				{ "name": "bool", "type": "BOOLEAN", "mode": "NULLABLE" },
+				{ "name": "str", "type": "STRING", "mode": "NULLABLE" },
+				{ "name": "bytes", "type": "BYTES", "mode": "NULLABLE" },
+				{ "name": "du", "type": "STRING", "mode": "NULLABLE" },
+				{ "name": "t", "type": "TIMESTAMP", "mode": "NULLABLE" }
 			]`,
 		})
 }
@@ -375,22 +446,22 @@ func TestModes(t *testing.T) {
 	testConvert(t, `
 			file_to_generate: "foo.proto"
 			proto_file <
-				Name: "foo.proto"
+				name: "foo.proto"
 				package: "example_package.nested"
 				message_type <
-					Name: "FooProto"
-					field < Name: "i1" number: 1 type: TYPE_INT32 label: LABEL_OPTIONAL >
-					field < Name: "i2" number: 2 type: TYPE_INT32 label: LABEL_REQUIRED >
-					field < Name: "i3" number: 3 type: TYPE_INT32 label: LABEL_REPEATED >
+					name: "FooProto"
+					field < name: "i1" number: 1 type: TYPE_INT32 label: LABEL_OPTIONAL >
+					field < name: "i2" number: 2 type: TYPE_INT32 label: LABEL_REQUIRED >
+					field < name: "i3" number: 3 type: TYPE_INT32 label: LABEL_REPEATED >
 					options < [gen_bq_schema.bigquery_opts] <table_name: "foo_table"> >
 				>
 			>
 		`,
 		map[string]string{
 			"example_package/nested/foo_table.schema": `[
-				{ "Name": "i1", "type": "INTEGER", "mode": "NULLABLE" },
-				{ "Name": "i2", "type": "INTEGER", "mode": "REQUIRED" },
-				{ "Name": "i3", "type": "INTEGER", "mode": "REPEATED" }
+				{ "name": "i1", "type": "INTEGER", "mode": "NULLABLE" },
+				{ "name": "i2", "type": "INTEGER", "mode": "REQUIRED" },
+				{ "name": "i3", "type": "INTEGER", "mode": "REPEATED" }
 			]`,
 		})
 }
@@ -399,12 +470,12 @@ func TestExtraFields(t *testing.T) {
 	testConvert(t, `
 			file_to_generate: "foo.proto"
 			proto_file <
-				Name: "foo.proto"
+				name: "foo.proto"
 				package: "example_package"
 				message_type <
-					Name: "FooProto"
+					name: "FooProto"
 					field <
-						Name: "i1"
+						name: "i1"
 						number: 1
 						type: TYPE_INT32
 						label: LABEL_OPTIONAL
@@ -417,38 +488,2042 @@ func TestExtraFields(t *testing.T) {
 								"i3:STRING:REPEATED",
 								"i4:TIMESTAMP:REQUIRED",
 								"i5:RECORD:example_package.nested2.BarProto",
-								"i6:RECORD:.google.protobuf.DoubleValue:REQUIRED"
+								"i6:RECORD:.google.protobuf.DoubleValue:REQUIRED",
+								" i7 : struct : nested2.BarProto : repeated "
 							]
 						>
 					>
 				>
 			>
 			proto_file <
-				Name: "bar.proto"
+				name: "bar.proto"
 				package: "example_package.nested2"
 				message_type <
-					Name: "BarProto"
-					field < Name: "i1" number: 1 type: TYPE_INT32 label: LABEL_OPTIONAL >
-					field < Name: "i2" number: 2 type: TYPE_INT32 label: LABEL_OPTIONAL >
-					field < Name: "i3" number: 3 type: TYPE_INT32 label: LABEL_OPTIONAL >
+					name: "BarProto"
+					field < name: "i1" number: 1 type: TYPE_INT32 label: LABEL_OPTIONAL >
+					field < name: "i2" number: 2 type: TYPE_INT32 label: LABEL_OPTIONAL >
+					field < name: "i3" number: 3 type: TYPE_INT32 label: LABEL_OPTIONAL >
+				>
+			>
+		`,
+		map[string]string{
+			"example_package/foo_table.schema": `[
+				{ "name": "i1", "type": "INTEGER", "mode": "NULLABLE" },
+				{ "name": "i2", "type": "INTEGER", "mode": "NULLABLE" },
+				{ "name": "i3", "type": "STRING", "mode": "REPEATED" },
+				{ "name": "i4", "type": "TIMESTAMP", "mode": "REQUIRED" },
+				{
+					"name": "i5", "type": "RECORD", "mode": "NULLABLE",
+					"fields": [
+						{ "name": "i1", "type": "INTEGER", "mode": "NULLABLE" },
+						{ "name": "i2", "type": "INTEGER", "mode": "NULLABLE" },
+						{ "name": "i3", "type": "INTEGER", "mode": "NULLABLE" }
+					]
+				},
+				{ "name": "i6", "type": "FLOAT", "mode": "REQUIRED" },
+				{
+					"name": "i7", "type": "RECORD", "mode": "REPEATED",
+					"fields": [
+						{ "name": "i1", "type": "INTEGER", "mode": "NULLABLE" },
+						{ "name": "i2", "type": "INTEGER", "mode": "NULLABLE" },
+						{ "name": "i3", "type": "INTEGER", "mode": "NULLABLE" }
+					]
+				}
+			]`,
+		})
+}
+
+func TestExtraFieldErrors(t *testing.T) {
+	input := `
+			file_to_generate: "foo.proto"
+			proto_file <
+				name: "foo.proto"
+				package: "example_package"
+				message_type <
+					name: "FooProto"
+					field < name: "i1" number: 1 type: TYPE_INT32 label: LABEL_OPTIONAL >
+					options < [gen_bq_schema.bigquery_opts] < table_name: "foo_table" extra_fields: "EXTRA" > >
+				>
+				message_type < name: "Empty" >
+			>
+		`
+	for spec, expectedError := range map[string]string{
+		"i2":                              `expected "<name>:<type>[:<mode>]" or "<name>:RECORD:<message type>[:<mode>]"`,
+		"i2:INTEGER:NULLABLE:x":           `expected "<name>:<type>[:<mode>]" or "<name>:RECORD:<message type>[:<mode>]"`,
+		"2i:INTEGER":                      `"2i" is not a valid BigQuery column name`,
+		"i2:INT":                          `unknown BigQuery type "INT"`,
+		"i2:INTEGER:OPTIONAL":             `unknown BigQuery mode "OPTIONAL"`,
+		"i2:RECORD":                       `RECORD fields require a message type, expected "<name>:<type>[:<mode>]" or "<name>:RECORD:<message type>[:<mode>]"`,
+		"I1:STRING":                       "a field named I1 already exists",
+		"i2:RECORD:Missing":               "cannot resolve message type Missing",
+		"i2:RECORD:example_package.Empty": "message type example_package.Empty has no fields",
+	} {
+		testConvertError(t, strings.Replace(input, "EXTRA", spec, 1),
+			fmt.Sprintf("Failed to convert foo.proto: invalid extra field %q of example_package.FooProto: %s", spec, expectedError))
+	}
+}
+
+// TestFieldOptions tests the generator with gen_bq_schema.bigquery field options on top-level and nested fields.
+func TestFieldOptions(t *testing.T) {
+	testConvert(t, `
+			file_to_generate: "foo.proto"
+			proto_file <
+				name: "foo.proto"
+				package: "example_package"
+				message_type <
+					name: "FooProto"
+					field <
+						name: "i1" number: 1 type: TYPE_INT32 label: LABEL_OPTIONAL
+						options < [gen_bq_schema.bigquery] < require: true > >
+					>
+					field <
+						name: "i2" number: 2 type: TYPE_INT32 label: LABEL_OPTIONAL
+						options < [gen_bq_schema.bigquery] < ignore: true > >
+					>
+					field <
+						name: "i3" number: 3 type: TYPE_UINT64 label: LABEL_OPTIONAL
+						options < [gen_bq_schema.bigquery] < type_override: "TIMESTAMP" > >
+					>
+					field <
+						name: "i4" number: 4 type: TYPE_STRING label: LABEL_OPTIONAL
+						options < [gen_bq_schema.bigquery] < name: "renamed" description: "Renamed field" > >
+					>
+					field <
+						name: "nested" number: 5 type: TYPE_MESSAGE label: LABEL_REPEATED
+						type_name: ".example_package.FooProto.Nested"
+						options < [gen_bq_schema.bigquery] < require: true > >
+					>
+					nested_type <
+						name: "Nested"
+						field <
+							name: "a" number: 1 type: TYPE_INT32 label: LABEL_OPTIONAL
+							options < [gen_bq_schema.bigquery] < require: true policy_tags: "private" > >
+						>
+						field <
+							name: "b" number: 2 type: TYPE_STRING label: LABEL_OPTIONAL
+							options < [gen_bq_schema.bigquery] < ignore: true > >
+						>
+					>
+					options < [gen_bq_schema.bigquery_opts] <table_name: "foo_table"> >
+				>
+			>
+		`,
+		map[string]string{
+			"example_package/foo_table.schema": `[
+				{ "name": "i1", "type": "INTEGER", "mode": "REQUIRED" },
+				{ "name": "i3", "type": "TIMESTAMP", "mode": "NULLABLE" },
+				{ "name": "renamed", "type": "STRING", "mode": "NULLABLE", "description": "Renamed field" },
+				{
+					"name": "nested", "type": "RECORD", "mode": "REPEATED",
+					"fields": [
+						{ "name": "a", "type": "INTEGER", "mode": "REQUIRED", "policyTags": { "names": ["private"] } }
+					]
+				}
+			]`,
+		})
+}
+
+// TestWellKnownJSONTypes tests the generator with well-known message packages which
+// are represented as JSON or as records in BigQuery.
+func TestWellKnownJSONTypes(t *testing.T) {
+	testConvert(t, `
+			file_to_generate: "foo.proto"
+			proto_file <
+				name: "foo.proto"
+				package: "example_package"
+				message_type <
+					name: "FooProto"
+					field <
+						name: "st" number: 1 type: TYPE_MESSAGE label: LABEL_OPTIONAL
+						type_name: ".google.protobuf.Struct"
+					>
+					field <
+						name: "v" number: 2 type: TYPE_MESSAGE label: LABEL_REPEATED
+						type_name: ".google.protobuf.Value"
+					>
+					field <
+						name: "lv" number: 3 type: TYPE_MESSAGE label: LABEL_OPTIONAL
+						type_name: ".google.protobuf.ListValue"
+					>
+					field <
+						name: "any" number: 4 type: TYPE_MESSAGE label: LABEL_OPTIONAL
+						type_name: ".google.protobuf.Any"
+					>
+					field <
+						name: "fm" number: 5 type: TYPE_MESSAGE label: LABEL_OPTIONAL
+						type_name: ".google.protobuf.FieldMask"
+					>
+					field <
+						name: "e" number: 6 type: TYPE_MESSAGE label: LABEL_OPTIONAL
+						type_name: ".google.protobuf.Empty"
+					>
+					options < [gen_bq_schema.bigquery_opts] <table_name: "foo_table"> >
+				>
+			>
+		`,
+		map[string]string{
+			"example_package/foo_table.schema": `[
+				{ "name": "st", "type": "JSON", "mode": "NULLABLE" },
+				{ "name": "v", "type": "JSON", "mode": "REPEATED" },
+				{ "name": "lv", "type": "JSON", "mode": "NULLABLE" },
+				{
+					"name": "any", "type": "RECORD", "mode": "NULLABLE",
+					"fields": [
+						{ "name": "type_url", "type": "STRING", "mode": "NULLABLE" },
+						{ "name": "value", "type": "BYTES", "mode": "NULLABLE" }
+					]
+				},
+				{ "name": "fm", "type": "STRING", "mode": "NULLABLE" }
+			]`,
+		})
+}
+
+// TestTypeResolution checks that message types are resolved by their fully-qualified names,
+// across packages and files, even when several messages share the same short name.
+func TestTypeResolution(t *testing.T) {
+	testConvert(t, `
+			file_to_generate: "foo.proto"
+			proto_file <
+				name: "foo.proto"
+				package: "example_package"
+				message_type <
+					name: "FooProto"
+					field <
+						name: "local_address" number: 1 type: TYPE_MESSAGE label: LABEL_OPTIONAL
+						type_name: ".example_package.Address"
+					>
+					field <
+						name: "other_address" number: 2 type: TYPE_MESSAGE label: LABEL_OPTIONAL
+						type_name: ".other.pkg.Address"
+					>
+					field <
+						name: "a" number: 3 type: TYPE_MESSAGE label: LABEL_OPTIONAL
+						type_name: ".example_package.FooProto.A"
+					>
+					field <
+						name: "b" number: 4 type: TYPE_MESSAGE label: LABEL_OPTIONAL
+						type_name: "FooProto.B"
+					>
+					nested_type <
+						name: "A"
+						field <
+							name: "metadata" number: 1 type: TYPE_MESSAGE label: LABEL_OPTIONAL
+							type_name: "Metadata"
+						>
+						nested_type <
+							name: "Metadata"
+							field < name: "a_meta" number: 1 type: TYPE_STRING label: LABEL_OPTIONAL >
+						>
+					>
+					nested_type <
+						name: "B"
+						field <
+							name: "metadata" number: 1 type: TYPE_MESSAGE label: LABEL_OPTIONAL
+							type_name: ".example_package.FooProto.B.Metadata"
+						>
+						nested_type <
+							name: "Metadata"
+							field < name: "b_meta" number: 1 type: TYPE_STRING label: LABEL_OPTIONAL >
+						>
+					>
+					options < [gen_bq_schema.bigquery_opts] <table_name: "foo_table"> >
+				>
+				message_type <
+					name: "Address"
+					field < name: "local" number: 1 type: TYPE_STRING label: LABEL_OPTIONAL >
+				>
+			>
+			proto_file <
+				name: "other.proto"
+				package: "other.pkg"
+				message_type <
+					name: "Address"
+					field < name: "other" number: 1 type: TYPE_STRING label: LABEL_OPTIONAL >
+				>
+			>
+		`,
+		map[string]string{
+			"example_package/foo_table.schema": `[
+				{
+					"name": "local_address", "type": "RECORD", "mode": "NULLABLE",
+					"fields": [{ "name": "local", "type": "STRING", "mode": "NULLABLE" }]
+				},
+				{
+					"name": "other_address", "type": "RECORD", "mode": "NULLABLE",
+					"fields": [{ "name": "other", "type": "STRING", "mode": "NULLABLE" }]
+				},
+				{
+					"name": "a", "type": "RECORD", "mode": "NULLABLE",
+					"fields": [{
+						"name": "metadata", "type": "RECORD", "mode": "NULLABLE",
+						"fields": [{ "name": "a_meta", "type": "STRING", "mode": "NULLABLE" }]
+					}]
+				},
+				{
+					"name": "b", "type": "RECORD", "mode": "NULLABLE",
+					"fields": [{
+						"name": "metadata", "type": "RECORD", "mode": "NULLABLE",
+						"fields": [{ "name": "b_meta", "type": "STRING", "mode": "NULLABLE" }]
+					}]
+				}
+			]`,
+		})
+
+	// Packages mapped with M parameters only move the outputs, types are still referred to by their
+	// declared packages.
+	testConvert(t, `
+			file_to_generate: "foo.proto"
+			file_to_generate: "bar.proto"
+			proto_file <
+				name: "foo.proto"
+				message_type <
+					name: "FooProto"
+					field < name: "b" number: 1 type: TYPE_MESSAGE label: LABEL_OPTIONAL type_name: ".Bar" >
+					field < name: "c" number: 2 type: TYPE_MESSAGE label: LABEL_OPTIONAL type_name: ".bar.Baz" >
+					options < [gen_bq_schema.bigquery_opts] <table_name: "foo_table"> >
+				>
+				message_type <
+					name: "Bar"
+					field < name: "i" number: 1 type: TYPE_INT32 label: LABEL_OPTIONAL >
+				>
+			>
+			proto_file <
+				name: "bar.proto"
+				package: "bar"
+				message_type <
+					name: "Baz"
+					field < name: "c" number: 1 type: TYPE_MESSAGE label: LABEL_OPTIONAL type_name: ".Bar" >
+					options < [gen_bq_schema.bigquery_opts] <table_name: "baz_table"> >
+				>
+			>
+		`,
+		map[string]string{
+			"mapped/foo_table.schema": `[
+				{
+					"name": "b", "type": "RECORD", "mode": "NULLABLE",
+					"fields": [{ "name": "i", "type": "INTEGER", "mode": "NULLABLE" }]
+				},
+				{
+					"name": "c", "type": "RECORD", "mode": "NULLABLE",
+					"fields": [{
+						"name": "c", "type": "RECORD", "mode": "NULLABLE",
+						"fields": [{ "name": "i", "type": "INTEGER", "mode": "NULLABLE" }]
+					}]
+				}
+			]`,
+			"other/mapped/baz_table.schema": `[
+				{
+					"name": "c", "type": "RECORD", "mode": "NULLABLE",
+					"fields": [{ "name": "i", "type": "INTEGER", "mode": "NULLABLE" }]
+				}
+			]`,
+		},
+		func(request *plugin.CodeGeneratorRequest) {
+			request.Parameter = proto.String("Mfoo.proto=mapped,Mbar.proto=other.mapped")
+		})
+
+	testConvertError(t, `
+			file_to_generate: "foo.proto"
+			proto_file <
+				name: "foo.proto"
+				package: "example_package"
+				message_type <
+					name: "FooProto"
+					field < name: "x" number: 1 type: TYPE_MESSAGE label: LABEL_OPTIONAL type_name: ".example_package.Missing" >
+					options < [gen_bq_schema.bigquery_opts] <table_name: "foo_table"> >
+				>
+			>
+		`,
+		"Failed to convert foo.proto: cannot resolve type .example_package.Missing of field example_package.FooProto.x")
+}
+
+// TestJSONNames checks that use_json_names names columns after the JSON names of fields, for nested fields too,
+// while the name field option still takes precedence.
+func TestJSONNames(t *testing.T) {
+	testConvert(t, `
+			file_to_generate: "foo.proto"
+			proto_file <
+				name: "foo.proto"
+				package: "example_package"
+				message_type <
+					name: "FooProto"
+					field < name: "snake_case" number: 1 type: TYPE_INT32 label: LABEL_OPTIONAL json_name: "snakeCase" >
+					field < name: "custom" number: 2 type: TYPE_INT32 label: LABEL_OPTIONAL json_name: "customJson" >
+					field < name: "no_json_name" number: 3 type: TYPE_INT32 label: LABEL_OPTIONAL >
+					field <
+						name: "renamed_field" number: 4 type: TYPE_INT32 label: LABEL_OPTIONAL json_name: "renamedField"
+						options < [gen_bq_schema.bigquery] < name: "renamed" > >
+					>
+					field <
+						name: "nested_msg" number: 5 type: TYPE_MESSAGE label: LABEL_OPTIONAL json_name: "nestedMsg"
+						type_name: ".example_package.FooProto.Nested"
+					>
+					nested_type <
+						name: "Nested"
+						field < name: "inner_field" number: 1 type: TYPE_STRING label: LABEL_OPTIONAL json_name: "innerField" >
+					>
+					options < [gen_bq_schema.bigquery_opts] <table_name: "foo_table" use_json_names: true> >
 				>
 			>
 		`,
 		map[string]string{
 			"example_package/foo_table.schema": `[
-				{ "Name": "i1", "type": "INTEGER", "mode": "NULLABLE" },
-				{ "Name": "i2", "type": "INTEGER", "mode": "NULLABLE" },
-				{ "Name": "i3", "type": "STRING", "mode": "REPEATED" },
-				{ "Name": "i4", "type": "TIMESTAMP", "mode": "REQUIRED" },
+				{ "name": "snakeCase", "type": "INTEGER", "mode": "NULLABLE" },
+				{ "name": "customJson", "type": "INTEGER", "mode": "NULLABLE" },
+				{ "name": "noJsonName", "type": "INTEGER", "mode": "NULLABLE" },
+				{ "name": "renamed", "type": "INTEGER", "mode": "NULLABLE" },
+				{
+					"name": "nestedMsg", "type": "RECORD", "mode": "NULLABLE",
+					"fields": [{ "name": "innerField", "type": "STRING", "mode": "NULLABLE" }]
+				}
+			]`,
+		})
+}
+
+const mapInput = `
+			file_to_generate: "foo.proto"
+			proto_file <
+				name: "foo.proto"
+				package: "example_package"
+				message_type <
+					name: "FooProto"
+					field <
+						name: "labels" number: 1 type: TYPE_MESSAGE label: LABEL_REPEATED
+						type_name: ".example_package.FooProto.LabelsEntry"
+					>
+					field <
+						name: "bars" number: 2 type: TYPE_MESSAGE label: LABEL_REPEATED
+						type_name: ".example_package.FooProto.BarsEntry"
+					>
+					field <
+						name: "attributes" number: 3 type: TYPE_MESSAGE label: LABEL_REPEATED
+						type_name: ".example_package.FooProto.AttributesEntry"
+						options < [gen_bq_schema.bigquery] < type_override: "JSON" > >
+					>
+					nested_type <
+						name: "LabelsEntry"
+						field < name: "key" number: 1 type: TYPE_STRING label: LABEL_OPTIONAL >
+						field < name: "value" number: 2 type: TYPE_STRING label: LABEL_OPTIONAL >
+						options < map_entry: true >
+					>
+					nested_type <
+						name: "BarsEntry"
+						field < name: "key" number: 1 type: TYPE_INT64 label: LABEL_OPTIONAL >
+						field <
+							name: "value" number: 2 type: TYPE_MESSAGE label: LABEL_OPTIONAL
+							type_name: ".example_package.Bar"
+						>
+						options < map_entry: true >
+					>
+					nested_type <
+						name: "AttributesEntry"
+						field < name: "key" number: 1 type: TYPE_STRING label: LABEL_OPTIONAL >
+						field < name: "value" number: 2 type: TYPE_STRING label: LABEL_OPTIONAL >
+						options < map_entry: true >
+					>
+					options < [gen_bq_schema.bigquery_opts] <table_name: "foo_table"> >
+				>
+				message_type <
+					name: "Bar"
+					field < name: "i1" number: 1 type: TYPE_INT32 label: LABEL_OPTIONAL >
+				>
+			>
+		`
+
+// TestMaps checks that map fields are rendered as repeated key/value records,
+// or as a single JSON column when overridden.
+func TestMaps(t *testing.T) {
+	testConvert(t, mapInput,
+		map[string]string{
+			"example_package/foo_table.schema": `[
+				{
+					"name": "labels", "type": "RECORD", "mode": "REPEATED",
+					"fields": [
+						{ "name": "key", "type": "STRING", "mode": "REQUIRED" },
+						{ "name": "value", "type": "STRING", "mode": "NULLABLE" }
+					]
+				},
 				{
-					"Name": "i5", "type": "RECORD", "mode": "NULLABLE",
+					"name": "bars", "type": "RECORD", "mode": "REPEATED",
 					"fields": [
-						{ "Name": "i1", "type": "INTEGER", "mode": "NULLABLE" },
-						{ "Name": "i2", "type": "INTEGER", "mode": "NULLABLE" },
-						{ "Name": "i3", "type": "INTEGER", "mode": "NULLABLE" }
+						{ "name": "key", "type": "INTEGER", "mode": "REQUIRED" },
+						{
+							"name": "value", "type": "RECORD", "mode": "NULLABLE",
+							"fields": [{ "name": "i1", "type": "INTEGER", "mode": "NULLABLE" }]
+						}
 					]
 				},
-				{ "Name": "i6", "type": "FLOAT", "mode": "REQUIRED" }
+				{ "name": "attributes", "type": "JSON", "mode": "NULLABLE" }
+			]`,
+		})
+}
+
+// TestMapsAsJSON checks that the maps=json parameter renders all map fields as JSON columns.
+func TestMapsAsJSON(t *testing.T) {
+	testConvert(t, mapInput,
+		map[string]string{
+			"example_package/foo_table.schema": `[
+				{ "name": "labels", "type": "JSON", "mode": "NULLABLE" },
+				{ "name": "bars", "type": "JSON", "mode": "NULLABLE" },
+				{ "name": "attributes", "type": "JSON", "mode": "NULLABLE" }
 			]`,
+		},
+		func(request *plugin.CodeGeneratorRequest) {
+			request.Parameter = proto.String("maps=json")
+		})
+
+	testConvertError(t, `
+			file_to_generate: "foo.proto"
+			proto_file < name: "foo.proto" package: "example_package" >
+		`,
+		`invalid value "sometimes" for parameter maps, expected one of records, json`,
+		func(request *plugin.CodeGeneratorRequest) {
+			request.Parameter = proto.String("maps=sometimes")
+		})
+}
+
+// TestOneofs checks the oneof strategies set in message and file options, and that synthetic oneofs
+// of proto3 optional fields are rendered as plain fields.
+func TestOneofs(t *testing.T) {
+	testConvert(t, `
+			file_to_generate: "foo.proto"
+			proto_file <
+				name: "foo.proto"
+				package: "example_package"
+				syntax: "proto3"
+				message_type <
+					name: "FooProto"
+					field < name: "i1" number: 1 type: TYPE_INT32 label: LABEL_OPTIONAL >
+					field < name: "a" number: 2 type: TYPE_INT32 label: LABEL_OPTIONAL oneof_index: 0 >
+					field < name: "b" number: 3 type: TYPE_STRING label: LABEL_OPTIONAL oneof_index: 0 >
+					field < name: "o" number: 4 type: TYPE_INT32 label: LABEL_OPTIONAL oneof_index: 1 proto3_optional: true >
+					oneof_decl < name: "choice" >
+					oneof_decl < name: "_o" >
+					options < [gen_bq_schema.bigquery_opts] <table_name: "foo_table" oneof_strategy: ONEOF_WRAP> >
+				>
+				message_type <
+					name: "BarProto"
+					field < name: "a" number: 1 type: TYPE_INT32 label: LABEL_OPTIONAL oneof_index: 0 >
+					field < name: "b" number: 2 type: TYPE_STRING label: LABEL_OPTIONAL oneof_index: 0 >
+					field < name: "o" number: 3 type: TYPE_INT32 label: LABEL_OPTIONAL oneof_index: 1 proto3_optional: true >
+					oneof_decl < name: "choice" >
+					oneof_decl < name: "_o" >
+					options < [gen_bq_schema.bigquery_opts] <table_name: "bar_table"> >
+				>
+				message_type <
+					name: "BazProto"
+					field < name: "a" number: 1 type: TYPE_INT32 label: LABEL_OPTIONAL oneof_index: 0 >
+					field < name: "b" number: 2 type: TYPE_STRING label: LABEL_OPTIONAL oneof_index: 0 >
+					oneof_decl < name: "choice" >
+					options < [gen_bq_schema.bigquery_opts] <table_name: "baz_table" oneof_strategy: ONEOF_FLATTEN> >
+				>
+				options < [gen_bq_schema.bigquery_file_opts] <oneof_strategy: ONEOF_DISCRIMINATOR> >
+			>
+		`,
+		map[string]string{
+			"example_package/foo_table.schema": `[
+				{ "name": "i1", "type": "INTEGER", "mode": "NULLABLE" },
+				{
+					"name": "choice", "type": "RECORD", "mode": "NULLABLE",
+					"fields": [
+						{ "name": "a", "type": "INTEGER", "mode": "NULLABLE" },
+						{ "name": "b", "type": "STRING", "mode": "NULLABLE" }
+					]
+				},
+				{ "name": "o", "type": "INTEGER", "mode": "NULLABLE" }
+			]`,
+			"example_package/bar_table.schema": `[
+				{ "name": "choice", "type": "STRING", "mode": "NULLABLE" },
+				{ "name": "a", "type": "INTEGER", "mode": "NULLABLE" },
+				{ "name": "b", "type": "STRING", "mode": "NULLABLE" },
+				{ "name": "o", "type": "INTEGER", "mode": "NULLABLE" }
+			]`,
+			"example_package/baz_table.schema": `[
+				{ "name": "a", "type": "INTEGER", "mode": "NULLABLE" },
+				{ "name": "b", "type": "STRING", "mode": "NULLABLE" }
+			]`,
+		})
+}
+
+// TestOneofsParameter checks that the oneofs parameter sets the strategy of messages without options.
+func TestOneofsParameter(t *testing.T) {
+	testConvert(t, `
+			file_to_generate: "foo.proto"
+			proto_file <
+				name: "foo.proto"
+				package: "example_package"
+				message_type <
+					name: "FooProto"
+					field < name: "a" number: 1 type: TYPE_INT32 label: LABEL_OPTIONAL oneof_index: 0 >
+					field < name: "b" number: 2 type: TYPE_STRING label: LABEL_OPTIONAL oneof_index: 0 >
+					oneof_decl < name: "choice" >
+					options < [gen_bq_schema.bigquery_opts] <table_name: "foo_table"> >
+				>
+			>
+		`,
+		map[string]string{
+			"example_package/foo_table.schema": `[
+				{
+					"name": "choice", "type": "RECORD", "mode": "NULLABLE",
+					"fields": [
+						{ "name": "a", "type": "INTEGER", "mode": "NULLABLE" },
+						{ "name": "b", "type": "STRING", "mode": "NULLABLE" }
+					]
+				}
+			]`,
+		},
+		func(request *plugin.CodeGeneratorRequest) {
+			request.Parameter = proto.String("oneofs=wrap")
+		})
+}
+
+const enumInput = `
+			file_to_generate: "foo.proto"
+			proto_file <
+				name: "foo.proto"
+				package: "example_package"
+				message_type <
+					name: "FooProto"
+					field <
+						name: "e1" number: 1 type: TYPE_ENUM label: LABEL_OPTIONAL
+						type_name: ".example_package.FooProto.Enum1"
+					>
+					field <
+						name: "e2" number: 2 type: TYPE_ENUM label: LABEL_REPEATED
+						type_name: ".example_package.Enum2"
+						options < [gen_bq_schema.bigquery] < enum_format: ENUM_NAME_AND_NUMBER > >
+					>
+					field <
+						name: "e3" number: 3 type: TYPE_ENUM label: LABEL_OPTIONAL
+						type_name: "Enum2"
+						options < [gen_bq_schema.bigquery] < enum_format: ENUM_STRING describe_enum_values: true > >
+					>
+					enum_type < name: "Enum1" value < name: "E1" number: 1 > value < name: "E2" number: 2 > >
+					options < [gen_bq_schema.bigquery_opts] <table_name: "foo_table"> >
+				>
+				enum_type < name: "Enum2" value < name: "UNKNOWN" number: 0 > value < name: "KNOWN" number: 1 > >
+			>
+		`
+
+// TestEnums checks the enum formats set in field options.
+func TestEnums(t *testing.T) {
+	testConvert(t, enumInput,
+		map[string]string{
+			"example_package/foo_table.schema": `[
+				{ "name": "e1", "type": "STRING", "mode": "NULLABLE" },
+				{
+					"name": "e2", "type": "RECORD", "mode": "REPEATED",
+					"fields": [
+						{ "name": "name", "type": "STRING", "mode": "NULLABLE" },
+						{ "name": "number", "type": "INTEGER", "mode": "NULLABLE" }
+					]
+				},
+				{
+					"name": "e3", "type": "STRING", "mode": "NULLABLE",
+					"description": "Allowed values: UNKNOWN = 0, KNOWN = 1"
+				}
+			]`,
+		})
+}
+
+// TestEnumsParameters checks the enums and enum-values parameters, which field options take precedence over.
+func TestEnumsParameters(t *testing.T) {
+	testConvert(t, enumInput,
+		map[string]string{
+			"example_package/foo_table.schema": `[
+				{
+					"name": "e1", "type": "INTEGER", "mode": "NULLABLE",
+					"description": "Allowed values: E1 = 1, E2 = 2"
+				},
+				{
+					"name": "e2", "type": "RECORD", "mode": "REPEATED",
+					"description": "Allowed values: UNKNOWN = 0, KNOWN = 1",
+					"fields": [
+						{ "name": "name", "type": "STRING", "mode": "NULLABLE" },
+						{ "name": "number", "type": "INTEGER", "mode": "NULLABLE" }
+					]
+				},
+				{
+					"name": "e3", "type": "STRING", "mode": "NULLABLE",
+					"description": "Allowed values: UNKNOWN = 0, KNOWN = 1"
+				}
+			]`,
+		},
+		func(request *plugin.CodeGeneratorRequest) {
+			request.Parameter = proto.String("enums=integer,enum-values")
+		})
+}
+
+const recursiveInput = `
+			file_to_generate: "foo.proto"
+			proto_file <
+				name: "foo.proto"
+				package: "example_package"
+				message_type <
+					name: "Thread"
+					field <
+						name: "root" number: 1 type: TYPE_MESSAGE label: LABEL_OPTIONAL
+						type_name: ".example_package.Comment"
+					>
+					options < [gen_bq_schema.bigquery_opts] <table_name: "thread_table"> >
+				>
+				message_type <
+					name: "Comment"
+					field < name: "text" number: 1 type: TYPE_STRING label: LABEL_OPTIONAL >
+					field <
+						name: "replies" number: 2 type: TYPE_MESSAGE label: LABEL_REPEATED
+						type_name: ".example_package.Comment"
+					>
+					options < [gen_bq_schema.bigquery_opts] <recursion_depth: 1 recursion_fallback: RECURSION_JSON> >
+				>
+			>
+		`
+
+// TestRecursionDepth checks that recursive messages are expanded up to the depth set in their options,
+// with the fallback rendering at the cut-off.
+func TestRecursionDepth(t *testing.T) {
+	testConvert(t, recursiveInput,
+		map[string]string{
+			"example_package/thread_table.schema": `[
+				{
+					"name": "root", "type": "RECORD", "mode": "NULLABLE",
+					"fields": [
+						{ "name": "text", "type": "STRING", "mode": "NULLABLE" },
+						{
+							"name": "replies", "type": "RECORD", "mode": "REPEATED",
+							"fields": [
+								{ "name": "text", "type": "STRING", "mode": "NULLABLE" },
+								{ "name": "replies", "type": "JSON", "mode": "REPEATED" }
+							]
+						}
+					]
+				}
+			]`,
+		})
+}
+
+// TestRecursionParameters checks that the message options take precedence over the recursion parameters.
+func TestRecursionParameters(t *testing.T) {
+	testConvert(t, `
+			file_to_generate: "foo.proto"
+			proto_file <
+				name: "foo.proto"
+				package: "example_package"
+				message_type <
+					name: "Comment"
+					field < name: "text" number: 1 type: TYPE_STRING label: LABEL_OPTIONAL >
+					field <
+						name: "replies" number: 2 type: TYPE_MESSAGE label: LABEL_REPEATED
+						type_name: ".example_package.Comment"
+					>
+					options < [gen_bq_schema.bigquery_opts] <table_name: "comment_table" recursion_fallback: RECURSION_STRING> >
+				>
+			>
+		`,
+		map[string]string{
+			"example_package/comment_table.schema": `[
+				{ "name": "text", "type": "STRING", "mode": "NULLABLE" },
+				{
+					"name": "replies", "type": "RECORD", "mode": "REPEATED",
+					"fields": [
+						{ "name": "text", "type": "STRING", "mode": "NULLABLE" },
+						{
+							"name": "replies", "type": "RECORD", "mode": "REPEATED",
+							"fields": [
+								{ "name": "text", "type": "STRING", "mode": "NULLABLE" },
+								{ "name": "replies", "type": "STRING", "mode": "REPEATED" }
+							]
+						}
+					]
+				}
+			]`,
+		},
+		func(request *plugin.CodeGeneratorRequest) {
+			request.Parameter = proto.String("recursion-depth=2,recursion-fallback=json")
+		})
+}
+
+// TestDDL checks the CREATE TABLE statements generated with format=ddl.
+func TestDDL(t *testing.T) {
+	testConvert(t, `
+			file_to_generate: "foo.proto"
+			proto_file <
+				name: "foo.proto"
+				package: "example_package"
+				message_type <
+					name: "FooProto"
+					field < name: "i1" number: 1 type: TYPE_INT32 label: LABEL_REQUIRED >
+					field < name: "d" number: 2 type: TYPE_DOUBLE label: LABEL_REPEATED >
+					field <
+						name: "nested" number: 3 type: TYPE_MESSAGE label: LABEL_REPEATED
+						type_name: ".example_package.FooProto.Nested"
+						options < [gen_bq_schema.bigquery] < description: "Say \"hi\"" > >
+					>
+					nested_type <
+						name: "Nested"
+						field < name: "b" number: 1 type: TYPE_BOOL label: LABEL_REQUIRED >
+						field < name: "t" number: 2 type: TYPE_MESSAGE label: LABEL_OPTIONAL type_name: ".google.protobuf.Timestamp" >
+					>
+					options < [gen_bq_schema.bigquery_opts] <table_name: "foo_table" description: "Foo table"> >
+				>
+			>
+		`,
+		map[string]string{
+			"example_package/foo_table.sql": strings.Join([]string{
+				"CREATE TABLE IF NOT EXISTS `project.dataset.foo_table` (",
+				"  `i1` INT64 NOT NULL,",
+				"  `d` ARRAY<FLOAT64>,",
+				"  `nested` ARRAY<STRUCT<",
+				"    `b` BOOL NOT NULL,",
+				"    `t` TIMESTAMP",
+				"  >> OPTIONS(description=\"Say \\\"hi\\\"\")",
+				")",
+				"OPTIONS(",
+				"  description=\"Foo table\"",
+				");",
+			}, "\n"),
+		},
+		func(request *plugin.CodeGeneratorRequest) {
+			request.Parameter = proto.String("format=ddl,project=project,dataset=dataset")
+		})
+}
+
+// TestPartitioning tests that partitioning and clustering options are emitted in a table resource
+// next to the schema.
+func TestPartitioning(t *testing.T) {
+	testConvert(t, `
+			file_to_generate: "foo.proto"
+			proto_file <
+				name: "foo.proto"
+				package: "example_package"
+				message_type <
+					name: "FooProto"
+					field < name: "id" number: 1 type: TYPE_STRING label: LABEL_OPTIONAL >
+					field < name: "created" number: 2 type: TYPE_MESSAGE label: LABEL_OPTIONAL type_name: ".google.protobuf.Timestamp" >
+					field < name: "kind" number: 3 type: TYPE_INT64 label: LABEL_OPTIONAL >
+					options <
+						[gen_bq_schema.bigquery_opts] <
+							table_name: "foo_table"
+							time_partitioning < type: "hour" field: "created" expiration_ms: 172800000 >
+							require_partition_filter: true
+							clustering_fields: [ "id", "kind" ]
+						>
+					>
+				>
+				message_type <
+					name: "BarProto"
+					field < name: "id" number: 1 type: TYPE_INT32 label: LABEL_OPTIONAL >
+					options <
+						[gen_bq_schema.bigquery_opts] <
+							table_name: "bar_table"
+							range_partitioning < field: "id" start: 0 end: 100 interval: 10 >
+						>
+					>
+				>
+			>
+		`,
+		map[string]string{
+			"example_package/foo_table.schema": `[
+				{ "name": "id", "type": "STRING", "mode": "NULLABLE" },
+				{ "name": "created", "type": "TIMESTAMP", "mode": "NULLABLE" },
+				{ "name": "kind", "type": "INTEGER", "mode": "NULLABLE" }
+			]`,
+			"example_package/foo_table.table.json": `{
+				"tableReference": { "tableId": "foo_table" },
+				"schema": {
+					"fields": [
+						{ "name": "id", "type": "STRING", "mode": "NULLABLE" },
+						{ "name": "created", "type": "TIMESTAMP", "mode": "NULLABLE" },
+						{ "name": "kind", "type": "INTEGER", "mode": "NULLABLE" }
+					]
+				},
+				"timePartitioning": { "type": "HOUR", "field": "created", "expirationMs": "172800000" },
+				"requirePartitionFilter": true,
+				"clustering": { "fields": [ "id", "kind" ] }
+			}`,
+			"example_package/bar_table.schema": `[
+				{ "name": "id", "type": "INTEGER", "mode": "NULLABLE" }
+			]`,
+			"example_package/bar_table.table.json": `{
+				"tableReference": { "tableId": "bar_table" },
+				"schema": { "fields": [ { "name": "id", "type": "INTEGER", "mode": "NULLABLE" } ] },
+				"rangePartitioning": { "field": "id", "range": { "start": "0", "end": "100", "interval": "10" } }
+			}`,
+		})
+
+	testConvertError(t, `
+			file_to_generate: "foo.proto"
+			proto_file <
+				name: "foo.proto"
+				package: "example_package"
+				message_type <
+					name: "BarProto"
+					field < name: "t" number: 1 type: TYPE_MESSAGE label: LABEL_REPEATED type_name: ".google.protobuf.Timestamp" >
+					options <
+						[gen_bq_schema.bigquery_opts] <
+							table_name: "bar_table"
+							time_partitioning < field: "t" >
+						>
+					>
+				>
+			>
+		`,
+		"Failed to convert foo.proto: time_partitioning field t of table bar_table cannot be REPEATED")
+}
+
+func TestPartitioningDDL(t *testing.T) {
+	testConvert(t, `
+			file_to_generate: "foo.proto"
+			proto_file <
+				name: "foo.proto"
+				package: "example_package"
+				message_type <
+					name: "FooProto"
+					field < name: "id" number: 1 type: TYPE_STRING label: LABEL_OPTIONAL >
+					field < name: "created" number: 2 type: TYPE_MESSAGE label: LABEL_OPTIONAL type_name: ".google.protobuf.Timestamp" >
+					options <
+						[gen_bq_schema.bigquery_opts] <
+							table_name: "foo_table"
+							time_partitioning < type: "MONTH" field: "created" expiration_ms: 129600000 >
+							require_partition_filter: true
+							clustering_fields: "id"
+						>
+					>
+				>
+				message_type <
+					name: "BarProto"
+					field < name: "id" number: 1 type: TYPE_INT32 label: LABEL_OPTIONAL >
+					options <
+						[gen_bq_schema.bigquery_opts] <
+							table_name: "bar_table"
+							range_partitioning < field: "id" start: 0 end: 100 interval: 10 >
+						>
+					>
+				>
+				message_type <
+					name: "BazProto"
+					field < name: "id" number: 1 type: TYPE_INT32 label: LABEL_OPTIONAL >
+					options <
+						[gen_bq_schema.bigquery_opts] <
+							table_name: "baz_table"
+							time_partitioning < >
+						>
+					>
+				>
+			>
+		`,
+		map[string]string{
+			"example_package/foo_table.sql": strings.Join([]string{
+				"CREATE TABLE IF NOT EXISTS `foo_table` (",
+				"  `id` STRING,",
+				"  `created` TIMESTAMP",
+				")",
+				"PARTITION BY TIMESTAMP_TRUNC(`created`, MONTH)",
+				"CLUSTER BY `id`",
+				"OPTIONS(",
+				"  partition_expiration_days=1.5,",
+				"  require_partition_filter=true",
+				");",
+			}, "\n"),
+			"example_package/bar_table.sql": strings.Join([]string{
+				"CREATE TABLE IF NOT EXISTS `bar_table` (",
+				"  `id` INT64",
+				")",
+				"PARTITION BY RANGE_BUCKET(`id`, GENERATE_ARRAY(0, 100, 10));",
+			}, "\n"),
+			"example_package/baz_table.sql": strings.Join([]string{
+				"CREATE TABLE IF NOT EXISTS `baz_table` (",
+				"  `id` INT64",
+				")",
+				"PARTITION BY _PARTITIONDATE;",
+			}, "\n"),
+		},
+		func(request *plugin.CodeGeneratorRequest) {
+			request.Parameter = proto.String("format=ddl")
+		})
+}
+
+func TestTableResource(t *testing.T) {
+	input := `
+			file_to_generate: "foo.proto"
+			proto_file <
+				name: "foo.proto"
+				package: "example_package"
+				message_type <
+					name: "FooProto"
+					field < name: "id" number: 1 type: TYPE_STRING label: LABEL_REQUIRED >
+					field < name: "day" number: 2 type: TYPE_STRING label: LABEL_OPTIONAL options < [gen_bq_schema.bigquery] < type_override: "DATE" > > >
+					options <
+						[gen_bq_schema.bigquery_opts] <
+							table_name: "foo_table"
+							description: "Foo table"
+							labels < key: "team" value: "data" >
+							labels < key: "env" value: "prod" >
+							expiration_time: 1767225600000
+							time_partitioning < field: "day" >
+							clustering_fields: "id"
+						>
+					>
+				>
+			>
+		`
+	testConvert(t, input,
+		map[string]string{
+			"example_package/foo_table.table.json": `{
+				"tableReference": { "projectId": "project", "datasetId": "dataset", "tableId": "foo_table" },
+				"description": "Foo table",
+				"labels": { "env": "prod", "team": "data" },
+				"schema": {
+					"fields": [
+						{ "name": "id", "type": "STRING", "mode": "REQUIRED" },
+						{ "name": "day", "type": "DATE", "mode": "NULLABLE" }
+					]
+				},
+				"timePartitioning": { "type": "DAY", "field": "day" },
+				"clustering": { "fields": [ "id" ] },
+				"expirationTime": "1767225600000"
+			}`,
+		},
+		func(request *plugin.CodeGeneratorRequest) {
+			request.Parameter = proto.String("format=table,project=project,dataset=dataset")
+		})
+
+	testConvert(t, input,
+		map[string]string{
+			"example_package/foo_table.sql": strings.Join([]string{
+				"CREATE TABLE IF NOT EXISTS `foo_table` (",
+				"  `id` STRING NOT NULL,",
+				"  `day` DATE",
+				")",
+				"PARTITION BY `day`",
+				"CLUSTER BY `id`",
+				"OPTIONS(",
+				"  description=\"Foo table\",",
+				"  expiration_timestamp=TIMESTAMP \"2026-01-01 00:00:00 UTC\",",
+				"  labels=[(\"env\", \"prod\"), (\"team\", \"data\")]",
+				");",
+			}, "\n"),
+		},
+		func(request *plugin.CodeGeneratorRequest) {
+			request.Parameter = proto.String("format=ddl")
+		})
+}
+
+func TestTerraform(t *testing.T) {
+	testConvert(t, `
+			file_to_generate: "foo.proto"
+			proto_file <
+				name: "foo.proto"
+				package: "example_package"
+				message_type <
+					name: "FooProto"
+					field < name: "id" number: 1 type: TYPE_INT64 label: LABEL_REQUIRED >
+					field < name: "tags" number: 2 type: TYPE_STRING label: LABEL_REPEATED >
+					options <
+						[gen_bq_schema.bigquery_opts] <
+							table_name: "foo_table"
+							description: "Foo table"
+							labels < key: "team" value: "data" >
+							range_partitioning < field: "id" start: 0 end: 1000 interval: 100 >
+							require_partition_filter: true
+						>
+					>
+				>
+			>
+		`,
+		map[string]string{
+			"example_package/foo_table.tf.json": `{
+				"resource": {
+					"google_bigquery_table": {
+						"foo_table": {
+							"project": "project",
+							"dataset_id": "dataset",
+							"table_id": "foo_table",
+							"description": "Foo table",
+							"labels": { "team": "data" },
+							"range_partitioning": { "field": "id", "range": { "start": 0, "end": 1000, "interval": 100 } },
+							"require_partition_filter": true,
+							"schema": "[{\"name\":\"id\",\"type\":\"INTEGER\",\"mode\":\"REQUIRED\"},{\"name\":\"tags\",\"type\":\"STRING\",\"mode\":\"REPEATED\"}]"
+						}
+					}
+				}
+			}`,
+		},
+		func(request *plugin.CodeGeneratorRequest) {
+			request.Parameter = proto.String("format=terraform,project=project,dataset=dataset")
+		})
+
+	// Terraform identifiers must start with a letter or an underscore.
+	testConvert(t, `
+			file_to_generate: "foo.proto"
+			proto_file <
+				name: "foo.proto"
+				package: "example_package"
+				message_type <
+					name: "FooProto"
+					field < name: "id" number: 1 type: TYPE_INT64 label: LABEL_REQUIRED >
+					options < [gen_bq_schema.bigquery_opts] < table_name: "1 comments" > >
+				>
+			>
+		`,
+		map[string]string{
+			"example_package/1 comments.tf.json": `{
+				"resource": {
+					"google_bigquery_table": {
+						"_1_comments": {
+							"dataset_id": "dataset",
+							"table_id": "1 comments",
+							"schema": "[{\"name\":\"id\",\"type\":\"INTEGER\",\"mode\":\"REQUIRED\"}]"
+						}
+					}
+				}
+			}`,
+		},
+		func(request *plugin.CodeGeneratorRequest) {
+			request.Parameter = proto.String("format=terraform,dataset=dataset")
+		})
+}
+
+// TestCompatibility tests that schemas are checked against the previously generated ones.
+func TestCompatibility(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "example_package"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	previous := `[
+		{ "name": "i1", "type": "INTEGER", "mode": "REQUIRED" },
+		{ "name": "nested", "type": "RECORD", "fields": [ { "name": "n", "type": "NUMERIC" } ] }
+	]`
+	if err := ioutil.WriteFile(filepath.Join(dir, "example_package", "foo_table.schema"), []byte(previous), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	testConvert(t, `
+			file_to_generate: "foo.proto"
+			proto_file <
+				name: "foo.proto"
+				package: "example_package"
+				message_type <
+					name: "FooProto"
+					field < name: "i1" number: 1 type: TYPE_INT64 label: LABEL_OPTIONAL >
+					field < name: "nested" number: 2 type: TYPE_MESSAGE label: LABEL_OPTIONAL type_name: ".example_package.FooProto.Nested" >
+					field < name: "s" number: 3 type: TYPE_STRING label: LABEL_REPEATED >
+					nested_type <
+						name: "Nested"
+						field < name: "n" number: 1 type: TYPE_DOUBLE label: LABEL_OPTIONAL >
+					>
+					options < [gen_bq_schema.bigquery_opts] <table_name: "foo_table"> >
+				>
+			>
+		`,
+		map[string]string{
+			"example_package/foo_table.schema": `[
+				{ "name": "i1", "type": "INTEGER", "mode": "NULLABLE" },
+				{
+					"name": "nested", "type": "RECORD", "mode": "NULLABLE",
+					"fields": [ { "name": "n", "type": "FLOAT", "mode": "NULLABLE" } ]
+				},
+				{ "name": "s", "type": "STRING", "mode": "REPEATED" }
+			]`,
+		},
+		func(request *plugin.CodeGeneratorRequest) {
+			request.Parameter = proto.String("previous-schemas=" + dir)
+		})
+}
+
+func TestIncompatibleChanges(t *testing.T) {
+	previous := Schema{
+		NewBQField("dropped", "STRING", "NULLABLE", ""),
+		NewBQField("retyped", "STRING", "NULLABLE", ""),
+		NewBQField("widened", "INTEGER", "NULLABLE", ""),
+		NewBQField("required", "STRING", "NULLABLE", ""),
+		NewBQField("repeated", "STRING", "REPEATED", ""),
+		NewBQField("relaxed", "STRING", "REQUIRED", ""),
+		NewBQField("nested", "RECORD", "NULLABLE", "", WithFields(Schema{
+			NewBQField("dropped", "STRING", "NULLABLE", ""),
+			NewBQField("kept", "BOOLEAN", "", ""),
+		})),
+	}
+	current := Schema{
+		NewBQField("Retyped", "INTEGER", "NULLABLE", ""),
+		NewBQField("widened", "FLOAT", "NULLABLE", ""),
+		NewBQField("required", "STRING", "REQUIRED", ""),
+		NewBQField("repeated", "STRING", "NULLABLE", ""),
+		NewBQField("relaxed", "STRING", "NULLABLE", ""),
+		NewBQField("nested", "STRUCT", "NULLABLE", "", WithFields(Schema{
+			NewBQField("kept", "BOOL", "NULLABLE", ""),
+			NewBQField("added", "STRING", "REQUIRED", ""),
+		})),
+		NewBQField("added", "STRING", "NULLABLE", ""),
+	}
+	expected := []string{
+		"column dropped was removed",
+		"column retyped changed type from STRING to INTEGER",
+		"column required changed mode from NULLABLE to REQUIRED",
+		"column repeated changed mode from REPEATED to NULLABLE",
+		"column nested.dropped was removed",
+		"column nested.added was added as REQUIRED",
+	}
+	if changes := incompatibleChanges("", previous, current); strings.Join(changes, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("incompatibleChanges() = %q, expected %q", changes, expected)
+	}
+}
+
+func TestMigration(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "example_package"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	previous := `[
+		{ "name": "i1", "type": "INTEGER", "mode": "REQUIRED" },
+		{ "name": "s", "type": "STRING", "mode": "NULLABLE", "description": "Old" },
+		{ "name": "nested", "type": "RECORD", "mode": "REPEATED", "fields": [ { "name": "b", "type": "BOOLEAN" } ] }
+	]`
+	if err := ioutil.WriteFile(filepath.Join(dir, "example_package", "foo_table.schema"), []byte(previous), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	testConvert(t, `
+			file_to_generate: "foo.proto"
+			proto_file <
+				name: "foo.proto"
+				package: "example_package"
+				message_type <
+					name: "FooProto"
+					field < name: "i1" number: 1 type: TYPE_INT64 label: LABEL_OPTIONAL >
+					field <
+						name: "s" number: 2 type: TYPE_STRING label: LABEL_OPTIONAL
+						options < [gen_bq_schema.bigquery] < description: "New" policy_tags: "private" > >
+					>
+					field < name: "nested" number: 3 type: TYPE_MESSAGE label: LABEL_REPEATED type_name: ".example_package.FooProto.Nested" >
+					field < name: "d" number: 4 type: TYPE_DOUBLE label: LABEL_OPTIONAL >
+					nested_type <
+						name: "Nested"
+						field < name: "b" number: 1 type: TYPE_BOOL label: LABEL_OPTIONAL >
+						field < name: "t" number: 2 type: TYPE_STRING label: LABEL_OPTIONAL >
+					>
+					options < [gen_bq_schema.bigquery_opts] <table_name: "foo_table"> >
+				>
+				message_type <
+					name: "BarProto"
+					field < name: "i1" number: 1 type: TYPE_INT64 label: LABEL_OPTIONAL >
+					options < [gen_bq_schema.bigquery_opts] <table_name: "bar_table"> >
+				>
+			>
+		`,
+		map[string]string{
+			"example_package/foo_table.migration.sql": strings.Join([]string{
+				"ALTER TABLE `dataset.foo_table` ALTER COLUMN `i1` DROP NOT NULL;",
+				"ALTER TABLE `dataset.foo_table` ALTER COLUMN `s` SET OPTIONS(description=\"New\");",
+				"-- policy tags of column s must be changed from [] to [private]",
+				"ALTER TABLE `dataset.foo_table` ALTER COLUMN `nested` SET DATA TYPE ARRAY<STRUCT<",
+				"  `b` BOOL,",
+				"  `t` STRING",
+				">>;",
+				"ALTER TABLE `dataset.foo_table` ADD COLUMN IF NOT EXISTS `d` FLOAT64;",
+			}, "\n"),
+			"example_package/bar_table.migration.sql": strings.Join([]string{
+				"CREATE TABLE IF NOT EXISTS `dataset.bar_table` (",
+				"  `i1` INT64",
+				");",
+			}, "\n"),
+		},
+		func(request *plugin.CodeGeneratorRequest) {
+			request.Parameter = proto.String("format=migration,dataset=dataset,previous-schemas=" + dir)
+		})
+}
+
+func TestValidateSchema(t *testing.T) {
+	msg := &ProtoType{Name: ".example_package.FooProto", File: &descriptor.FileDescriptorProto{Name: proto.String("foo.proto")}}
+	deep := NewBQField("leaf", "STRING", "NULLABLE", "")
+	for depth := 0; depth < 16; depth++ {
+		deep = NewBQField("deep", "RECORD", "NULLABLE", "", WithFields(Schema{deep}))
+	}
+	table := &Table{
+		Name: "foo.table",
+		Schema: Schema{
+			NewBQField("a", "STRING", "NULLABLE", ""),
+			NewBQField("A", "STRING", "NULLABLE", ""),
+			NewBQField("a-b", "STRING", "NULLABLE", ""),
+			NewBQField("_partitiontime", "TIMESTAMP", "NULLABLE", ""),
+			NewBQField("long", "STRING", "NULLABLE", strings.Repeat("x", 1025)),
+			NewBQField("empty", "RECORD", "NULLABLE", ""),
+			deep,
+		},
+	}
+	expected := strings.Join([]string{
+		"invalid schema of table foo.table for message example_package.FooProto in foo.proto:",
+		"  invalid table name, expected letters, numbers, underscores, dashes and spaces",
+		"  column A: duplicate name, conflicts with a",
+		"  column a-b: invalid name, expected letters, numbers and underscores starting with a letter or an underscore",
+		"  column _partitiontime: names starting with _PARTITION are reserved",
+		"  column long: description is 1025 characters long, at most 1024 are allowed",
+		"  column empty: RECORD has no fields",
+		"  column " + strings.Repeat("deep.", 15) + "deep: RECORD is nested more than 15 levels deep",
+	}, "\n")
+	if err := validateSchema(msg, table); err == nil || err.Error() != expected {
+		t.Errorf("validateSchema() = %v, expected %s", err, expected)
+	}
+
+	table.Schema = make(Schema, maxColumns+1)
+	for idx := range table.Schema {
+		table.Schema[idx] = NewBQField(fmt.Sprintf("c%d", idx), "STRING", "NULLABLE", "")
+	}
+	if err := validateSchema(msg, table); err == nil || !strings.HasSuffix(err.Error(), "table has 10001 columns, at most 10000 are allowed") {
+		t.Errorf("validateSchema() = %v, expected too many columns", err)
+	}
+
+	input := `
+			file_to_generate: "foo.proto"
+			proto_file <
+				name: "foo.proto"
+				package: "example_package"
+				message_type <
+					name: "FooProto"
+					field < name: "e" number: 1 type: TYPE_MESSAGE label: LABEL_OPTIONAL type_name: ".google.protobuf.Empty" >
+					field < name: "i" number: 2 type: TYPE_MESSAGE label: LABEL_REPEATED type_name: ".example_package.Ignored" >
+					field < name: "n" number: 3 type: TYPE_MESSAGE label: LABEL_OPTIONAL type_name: ".example_package.Empty" >
+					options < [gen_bq_schema.bigquery_opts] <table_name: "foo_table"> >
+				>
+				message_type <
+					name: "Ignored"
+					field <
+						name: "x" number: 1 type: TYPE_STRING label: LABEL_OPTIONAL
+						options < [gen_bq_schema.bigquery] < ignore: true > >
+					>
+				>
+				message_type < name: "Empty" >
+			>
+		`
+	testConvertError(t, input, strings.Join([]string{
+		"Failed to convert foo.proto: invalid schema of table foo_table for message example_package.FooProto in foo.proto:",
+		"  column i: RECORD has no fields",
+		"  column n: RECORD has no fields",
+	}, "\n"))
+}
+
+// TestGenerator tests that a Generator applies its default parameters, and leaves requests untouched.
+func TestGenerator(t *testing.T) {
+	req := &plugin.CodeGeneratorRequest{
+		FileToGenerate: []string{"foo/bar.proto"},
+		Parameter:      proto.String("single-message,format=schema"),
+		ProtoFile: []*descriptor.FileDescriptorProto{{
+			Name:    proto.String("foo/bar.proto"),
+			Package: proto.String("foo"),
+			MessageType: []*descriptor.DescriptorProto{
+				{Name: proto.String("Bar"), Field: []*descriptor.FieldDescriptorProto{{
+					Name:   proto.String("a"),
+					Number: proto.Int32(1),
+					Type:   descriptor.FieldDescriptorProto_TYPE_INT32.Enum(),
+					Label:  descriptor.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+				}}},
+				{Name: proto.String("Baz")},
+			},
+		}},
+	}
+	original := proto.Clone(req)
+
+	res, err := NewGenerator(WithParameter("format=ddl"), WithParameter("dataset=dataset")).Generate(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Error != nil {
+		t.Fatalf("Generate() failed: %s", res.GetError())
+	}
+	if len(res.File) != 1 || res.File[0].GetName() != "foo/bar.schema" {
+		t.Errorf("Generate() = %v, expected foo/bar.schema", res.File)
+	}
+	if !proto.Equal(req, original) {
+		t.Errorf("Generate() changed the request to %v", req)
+	}
+
+	res, err = NewGenerator(WithParameter("format=csv")).Generate(&plugin.CodeGeneratorRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := `invalid value "csv" for parameter format, expected one of schema, ddl, table, terraform, migration`; res.GetError() != expected {
+		t.Errorf("Generate() failed with %q, expected %q", res.GetError(), expected)
+	}
+}
+
+// goldenExamples lists the examples generated into examples/, along with the parameters they are
+// generated with and the files they generate. Their descriptor sets are built into examples/descriptors
+// with `make descriptors`.
+var goldenExamples = []struct {
+	file      string
+	parameter string
+	outputs   []string
+}{
+	{"foo.proto", "", []string{"foo/bar_table.schema"}},
+	{"foo-proto3.proto", "", []string{"foo/bar_proto3_table.schema"}},
+	{"single_message.proto", "single-message", []string{"foo/single_message.schema"}},
+	{"test_table.proto", "", []string{"foo/test_table.schema"}},
+}
+
+// TestGolden generates the examples, and compares the outputs with the files under examples/, which must
+// all be generated by an example. With -update, the files are rewritten instead, and the files no example
+// generates are removed.
+func TestGolden(t *testing.T) {
+	generated := make(map[string]bool)
+	ran := 0
+	for _, example := range goldenExamples {
+		t.Run(example.file, func(t *testing.T) {
+			ran++
+			data, err := ioutil.ReadFile(filepath.Join("..", "examples", "descriptors", strings.TrimSuffix(example.file, ".proto")+".pb"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			set := &descriptor.FileDescriptorSet{}
+			if err = proto.Unmarshal(data, set); err != nil {
+				t.Fatal(err)
+			}
+			res, err := NewGenerator().Generate(&plugin.CodeGeneratorRequest{
+				FileToGenerate: []string{example.file},
+				Parameter:      proto.String(example.parameter),
+				ProtoFile:      set.GetFile(),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.Error != nil {
+				t.Fatal("Failed to convert: ", res.GetError())
+			}
+
+			var outputs []string
+			for _, file := range res.GetFile() {
+				outputs = append(outputs, file.GetName())
+				generated[file.GetName()] = true
+				path := filepath.Join("..", "examples", filepath.FromSlash(file.GetName()))
+				if *update {
+					if err = ioutil.WriteFile(path, []byte(file.GetContent()), 0644); err != nil {
+						t.Fatal(err)
+					}
+					continue
+				}
+				expected, err := ioutil.ReadFile(path)
+				if err != nil {
+					t.Errorf("Failed to read golden file: %v", err)
+					continue
+				}
+				if string(expected) != file.GetContent() {
+					t.Errorf("%s is out of date, regenerate it with `go test ./pkg -run TestGolden -update`:\n%s", path, diffLines(string(expected), file.GetContent()))
+				}
+			}
+			sort.Strings(outputs)
+			if !reflect.DeepEqual(outputs, example.outputs) {
+				t.Errorf("Expected %s to generate %v, got %v", example.file, example.outputs, outputs)
+			}
+		})
+	}
+
+	// Golden files are the files under examples/ other than the protos and their descriptor sets, and
+	// only the run of every example tells which of them are generated.
+	if ran < len(goldenExamples) {
+		return
+	}
+	root := filepath.Join("..", "examples")
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() && info.Name() == "descriptors" {
+			return filepath.SkipDir
+		}
+		if info.IsDir() || filepath.Ext(path) == ".proto" {
+			return nil
+		}
+		name, err := filepath.Rel(root, path)
+		if err != nil || generated[filepath.ToSlash(name)] {
+			return err
+		}
+		if *update {
+			return os.Remove(path)
+		}
+		t.Errorf("%s is not generated by any example, remove it with `go test ./pkg -run TestGolden -update`", path)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// diffLines describes the first line which differs between two texts.
+func diffLines(expected, actual string) string {
+	expectedLines, actualLines := strings.Split(expected, "\n"), strings.Split(actual, "\n")
+	for idx := 0; idx < len(expectedLines) || idx < len(actualLines); idx++ {
+		var e, a string
+		if idx < len(expectedLines) {
+			e = expectedLines[idx]
+		}
+		if idx < len(actualLines) {
+			a = actualLines[idx]
+		}
+		if e != a {
+			return fmt.Sprintf("line %d:\n- %s\n+ %s", idx+1, e, a)
+		}
+	}
+	return ""
+}
+
+// TestDescriptorSet tests generating messages from a descriptor set, as the standalone mode does.
+func TestDescriptorSet(t *testing.T) {
+	set, err := ReadDescriptorSets(filepath.Join("..", "examples", "descriptors", "test_table.pb") + string(filepath.ListSeparator) +
+		filepath.Join("..", "examples", "descriptors", "foo.pb"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, messages, err := NewRequest(set, "format=ddl", []string{"foo.Bar", "test_table.proto"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(req.GetFileToGenerate(), ",") != "foo.proto,test_table.proto" || strings.Join(messages, ",") != "foo.Bar" {
+		t.Errorf("NewRequest() = %v, %v, expected foo.proto and test_table.proto, restricted to foo.Bar", req.GetFileToGenerate(), messages)
+	}
+	res, err := NewGenerator(WithMessages(messages...)).Generate(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, file := range res.GetFile() {
+		names = append(names, file.GetName())
+	}
+	if res.Error != nil || strings.Join(names, ",") != "foo/test_table.sql,foo/bar_table.sql" {
+		t.Errorf("Generate() = %v, %s, expected foo/test_table.sql and foo/bar_table.sql", names, res.GetError())
+	}
+
+	if _, _, err = NewRequest(set, "", []string{"foo.Missing"}); err == nil {
+		t.Error("NewRequest() succeeded with an unknown message")
+	}
+
+	if req, messages, err = NewRequest(set, "", []string{"foo.Baz"}); err != nil {
+		t.Fatal(err)
+	}
+	if res, err = NewGenerator(WithMessages(messages...)).Generate(req); err != nil {
+		t.Fatal(err)
+	}
+	expected := "Failed to convert foo.proto: message foo.Baz is selected but has no table name, set the table_name option or include it"
+	if res.GetError() != expected {
+		t.Errorf("Generate() failed with %q, expected %q", res.GetError(), expected)
+	}
+}
+
+func TestPresence(t *testing.T) {
+	input := `
+			file_to_generate: "foo.proto"
+			proto_file <
+				name: "foo.proto"
+				package: "example_package"
+				syntax: "proto3"
+				message_type <
+					name: "FooProto"
+					field < name: "i" number: 1 type: TYPE_INT32 label: LABEL_OPTIONAL >
+					field < name: "opt" number: 2 type: TYPE_INT32 label: LABEL_OPTIONAL oneof_index: 0 proto3_optional: true >
+					field < name: "choice" number: 3 type: TYPE_STRING label: LABEL_OPTIONAL oneof_index: 1 >
+					field < name: "t" number: 4 type: TYPE_MESSAGE label: LABEL_OPTIONAL type_name: ".google.protobuf.Timestamp" >
+					field < name: "r" number: 5 type: TYPE_STRING label: LABEL_REPEATED >
+					oneof_decl < name: "_opt" >
+					oneof_decl < name: "kind" >
+					options < [gen_bq_schema.bigquery_opts] <table_name: "foo_table"> >
+				>
+			>
+		`
+	testConvert(t, input,
+		map[string]string{
+			"example_package/foo_table.schema": `[
+				{ "name": "i", "type": "INTEGER", "mode": "NULLABLE" },
+				{ "name": "opt", "type": "INTEGER", "mode": "NULLABLE" },
+				{ "name": "choice", "type": "STRING", "mode": "NULLABLE" },
+				{ "name": "t", "type": "TIMESTAMP", "mode": "NULLABLE" },
+				{ "name": "r", "type": "STRING", "mode": "REPEATED" }
+			]`,
+		})
+	testConvert(t, input,
+		map[string]string{
+			"example_package/foo_table.schema": `[
+				{ "name": "i", "type": "INTEGER", "mode": "REQUIRED" },
+				{ "name": "opt", "type": "INTEGER", "mode": "NULLABLE" },
+				{ "name": "choice", "type": "STRING", "mode": "NULLABLE" },
+				{ "name": "t", "type": "TIMESTAMP", "mode": "NULLABLE" },
+				{ "name": "r", "type": "STRING", "mode": "REPEATED" }
+			]`,
+		},
+		func(request *plugin.CodeGeneratorRequest) {
+			request.Parameter = proto.String("implicit-presence=required")
+		})
+}
+
+// setFieldPresence sets the field_presence feature of Editions in options, where the features are the field
+// with the given number. The descriptors this plugin is built with keep them as unknown fields.
+func setFieldPresence(options proto.Message, featuresNumber protowire.Number, presence fieldPresence) {
+	features := protowire.AppendTag(nil, fieldPresenceNumber, protowire.VarintType)
+	features = protowire.AppendVarint(features, uint64(presence))
+	field := protowire.AppendTag(nil, featuresNumber, protowire.BytesType)
+	field = protowire.AppendBytes(field, features)
+	m := options.ProtoReflect()
+	m.SetUnknown(append(m.GetUnknown(), field...))
+}
+
+func TestEditionsPresence(t *testing.T) {
+	testConvert(t, `
+			file_to_generate: "foo.proto"
+			proto_file <
+				name: "foo.proto"
+				package: "example_package"
+				syntax: "editions"
+				message_type <
+					name: "FooProto"
+					field < name: "inherited" number: 1 type: TYPE_INT32 label: LABEL_OPTIONAL >
+					field < name: "required" number: 2 type: TYPE_INT32 label: LABEL_OPTIONAL options < > >
+					field < name: "explicit" number: 3 type: TYPE_INT32 label: LABEL_OPTIONAL options < > >
+					field < name: "inner" number: 4 type: TYPE_MESSAGE label: LABEL_OPTIONAL type_name: ".example_package.FooProto.Inner" >
+					field <
+						name: "required_inner" number: 5 type: TYPE_MESSAGE label: LABEL_OPTIONAL
+						type_name: ".example_package.FooProto.Inner" options < >
+					>
+					nested_type <
+						name: "Inner"
+						field < name: "i" number: 1 type: TYPE_INT32 label: LABEL_OPTIONAL >
+						options < >
+					>
+					options < [gen_bq_schema.bigquery_opts] <table_name: "foo_table"> >
+				>
+				options < >
+			>
+		`,
+		map[string]string{
+			"example_package/foo_table.schema": `[
+				{ "name": "inherited", "type": "INTEGER", "mode": "REQUIRED" },
+				{ "name": "required", "type": "INTEGER", "mode": "REQUIRED" },
+				{ "name": "explicit", "type": "INTEGER", "mode": "NULLABLE" },
+				{
+					"name": "inner", "type": "RECORD", "mode": "NULLABLE",
+					"fields": [{ "name": "i", "type": "INTEGER", "mode": "NULLABLE" }]
+				},
+				{
+					"name": "required_inner", "type": "RECORD", "mode": "REQUIRED",
+					"fields": [{ "name": "i", "type": "INTEGER", "mode": "NULLABLE" }]
+				}
+			]`,
+		},
+		func(request *plugin.CodeGeneratorRequest) {
+			request.Parameter = proto.String("implicit-presence=required")
+			file := request.GetProtoFile()[0]
+			setFieldPresence(file.GetOptions(), fileFeaturesNumber, presenceImplicit)
+			msg := file.GetMessageType()[0]
+			setFieldPresence(msg.GetField()[1].GetOptions(), fieldFeaturesNumber, presenceLegacyRequired)
+			setFieldPresence(msg.GetField()[2].GetOptions(), fieldFeaturesNumber, presenceExplicit)
+			setFieldPresence(msg.GetField()[4].GetOptions(), fieldFeaturesNumber, presenceLegacyRequired)
+			setFieldPresence(msg.GetNestedType()[0].GetOptions(), messageFeaturesNumber, presenceExplicit)
+		})
+}
+
+func TestParameters(t *testing.T) {
+	params, err := ParseParameters(" format=ddl, enum-values,Mfoo/single-message.proto=bar,format=table,enum-values=false,,dataset=d")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &Parameters{
+		Packages:         map[string]string{"foo/single-message.proto": "bar"},
+		Maps:             mapsRecords,
+		ImplicitPresence: implicitPresenceNullable,
+		Format:           formatTable,
+		Dataset:          "d",
+	}
+	if !reflect.DeepEqual(params, expected) {
+		t.Errorf("ParseParameters() = %+v, expected %+v", params, expected)
+	}
+
+	for parameter, expectedError := range map[string]string{
+		"M":                      `unknown parameter "M"`,
+		"Maps=json":              `unknown parameter "Maps"`,
+		"Mfoo.proto":             `invalid parameter "Mfoo.proto", expected M<file>=<package>`,
+		"single-messages":        `unknown parameter "single-messages"`,
+		"format":                 "parameter format requires a value",
+		"enum-values=sometimes":  `invalid value "sometimes" for parameter enum-values, expected true or false`,
+		"recursion-depth=-1":     `invalid value "-1" for parameter recursion-depth, expected a non-negative integer`,
+		"format=terraform":       "parameter dataset is required with format=terraform",
+		"format=migration":       "parameter previous-schemas is required with format=migration",
+		"oneofs=wrap,enums=json": `invalid value "json" for parameter enums, expected one of string, integer, both`,
+	} {
+		if _, err := ParseParameters(parameter); err == nil || err.Error() != expectedError {
+			t.Errorf("ParseParameters(%q) failed with %v, expected %q", parameter, err, expectedError)
+		}
+	}
+
+	testConvertError(t, `
+			file_to_generate: "foo.proto"
+			proto_file < name: "foo.proto" package: "example_package" >
+		`,
+		`unknown parameter "single-messages"`,
+		func(request *plugin.CodeGeneratorRequest) {
+			request.Parameter = proto.String("single-messages")
+		})
+}
+
+func TestParametersDocumented(t *testing.T) {
+	readme, err := ioutil.ReadFile("../README.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, def := range parameterDefinitions {
+		if !strings.Contains(string(readme), "| `"+def.name) {
+			t.Errorf("parameter %s is not listed in the parameters of README.md", def.name)
+		}
+	}
+}
+
+func TestOverrides(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "overrides.json")
+	overrides := `{
+		"vendor.Event": {
+			"table_name": "events",
+			"fields": {
+				"id": { "mode": "REQUIRED", "description": "ID of the event" },
+				"secret": { "ignore": true },
+				"payload": { "name": "data", "type_override": "JSON" },
+				"email": { "policy_tags": "projects/p/locations/us/taxonomies/t/policyTags/pii", "mode": "NULLABLE" },
+				"annotated": { "ignore": false }
+			}
+		},
+		"vendor.Event.Inner": { "fields": { "n": { "type_override": "BIGNUMERIC" } } }
+	}`
+	if err := ioutil.WriteFile(path, []byte(overrides), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	input := `
+			file_to_generate: "vendor.proto"
+			proto_file <
+				name: "vendor.proto"
+				package: "vendor"
+				message_type <
+					name: "Event"
+					field < name: "id" number: 1 type: TYPE_STRING label: LABEL_OPTIONAL >
+					field < name: "secret" number: 2 type: TYPE_STRING label: LABEL_OPTIONAL >
+					field < name: "payload" number: 3 type: TYPE_STRING label: LABEL_OPTIONAL >
+					field < name: "email" number: 4 type: TYPE_STRING label: LABEL_REQUIRED >
+					field <
+						name: "annotated" number: 5 type: TYPE_STRING label: LABEL_OPTIONAL
+						options < [gen_bq_schema.bigquery] < ignore: true > >
+					>
+					field < name: "inner" number: 6 type: TYPE_MESSAGE label: LABEL_OPTIONAL type_name: ".vendor.Event.Inner" >
+					nested_type <
+						name: "Inner"
+						field < name: "n" number: 1 type: TYPE_INT64 label: LABEL_OPTIONAL >
+					>
+				>
+			>
+		`
+	testConvert(t, input,
+		map[string]string{
+			"vendor/events.schema": `[
+				{ "name": "id", "type": "STRING", "mode": "REQUIRED", "description": "ID of the event" },
+				{ "name": "data", "type": "JSON", "mode": "NULLABLE" },
+				{
+					"name": "email", "type": "STRING", "mode": "NULLABLE",
+					"policyTags": { "names": ["projects/p/locations/us/taxonomies/t/policyTags/pii"] }
+				},
+				{ "name": "annotated", "type": "STRING", "mode": "NULLABLE" },
+				{
+					"name": "inner", "type": "RECORD", "mode": "NULLABLE",
+					"fields": [{ "name": "n", "type": "BIGNUMERIC", "mode": "NULLABLE" }]
+				}
+			]`,
+		},
+		func(request *plugin.CodeGeneratorRequest) {
+			request.Parameter = proto.String("overrides=" + path)
+		})
+
+	overrides = `{
+		"vendor.Missing": { "table_name": "missing" },
+		"other.Missing": { "table_name": "other" },
+		"vendor.Event": { "fields": { "ID": {}, "id": { "mode": "OPTIONAL" }, "payload": { "type_override": "JSONB" } } }
+	}`
+	if err := ioutil.WriteFile(path, []byte(overrides), 0644); err != nil {
+		t.Fatal(err)
+	}
+	testConvertError(t, input,
+		"invalid overrides:\n"+
+			"  field vendor.Event.ID not found\n"+
+			`  invalid mode "OPTIONAL" of field vendor.Event.id, expected one of NULLABLE, REQUIRED`+"\n"+
+			"  message vendor.Missing not found\n"+
+			`  unknown BigQuery type "JSONB" of field vendor.Event.payload`,
+		func(request *plugin.CodeGeneratorRequest) {
+			request.Parameter = proto.String("overrides=" + path)
+		})
+
+	if err := ioutil.WriteFile(path, []byte(`{ "vendor.Event": { "table": "events" } }`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	testConvertError(t, input,
+		`cannot parse overrides `+path+`: json: unknown field "table"`,
+		func(request *plugin.CodeGeneratorRequest) {
+			request.Parameter = proto.String("overrides=" + path)
+		})
+
+	yamlPath := filepath.Join(filepath.Dir(path), "overrides.yaml")
+	overrides = `
+vendor.Event:
+  table_name: events
+  fields:
+    secret: { ignore: true }
+    payload: { type_override: JSON }
+    inner: { ignore: true }
+`
+	if err := ioutil.WriteFile(yamlPath, []byte(overrides), 0644); err != nil {
+		t.Fatal(err)
+	}
+	testConvert(t, input,
+		map[string]string{
+			"vendor/events.schema": `[
+				{ "name": "id", "type": "STRING", "mode": "NULLABLE" },
+				{ "name": "payload", "type": "JSON", "mode": "NULLABLE" },
+				{ "name": "email", "type": "STRING", "mode": "REQUIRED" }
+			]`,
+		},
+		func(request *plugin.CodeGeneratorRequest) {
+			request.Parameter = proto.String("overrides=" + yamlPath)
+		})
+
+	if err := ioutil.WriteFile(yamlPath, []byte("vendor.Event:\n  table: events\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	testConvertError(t, input,
+		`cannot parse overrides `+yamlPath+`: json: unknown field "table"`,
+		func(request *plugin.CodeGeneratorRequest) {
+			request.Parameter = proto.String("overrides=" + yamlPath)
+		})
+}
+
+func TestInclude(t *testing.T) {
+	input := `
+			file_to_generate: "foo/events.proto"
+			proto_file <
+				name: "foo/events.proto"
+				package: "foo.events"
+				message_type <
+					name: "PageView"
+					field < name: "url" number: 1 type: TYPE_STRING label: LABEL_OPTIONAL >
+				>
+				message_type <
+					name: "HTTPRequestLog"
+					field < name: "status" number: 1 type: TYPE_INT32 label: LABEL_OPTIONAL >
+				>
+				message_type <
+					name: "Click"
+					field < name: "x" number: 1 type: TYPE_INT32 label: LABEL_OPTIONAL >
+					options < [gen_bq_schema.bigquery_opts] <table_name: "clicks"> >
+				>
+			>
+		`
+	testConvert(t, input,
+		map[string]string{
+			"foo/events/clicks.schema": `[{ "name": "x", "type": "INTEGER", "mode": "NULLABLE" }]`,
+		})
+	testConvert(t, input,
+		map[string]string{
+			"foo/events/page_view.schema":        `[{ "name": "url", "type": "STRING", "mode": "NULLABLE" }]`,
+			"foo/events/http_request_log.schema": `[{ "name": "status", "type": "INTEGER", "mode": "NULLABLE" }]`,
+			"foo/events/clicks.schema":           `[{ "name": "x", "type": "INTEGER", "mode": "NULLABLE" }]`,
+		},
+		func(request *plugin.CodeGeneratorRequest) {
+			request.Parameter = proto.String("include=foo.events.*")
+		})
+	testConvert(t, input,
+		map[string]string{
+			"foo/events/pageview_v1.schema": `[{ "name": "url", "type": "STRING", "mode": "NULLABLE" }]`,
+			"foo/events/clicks.schema":      `[{ "name": "x", "type": "INTEGER", "mode": "NULLABLE" }]`,
+		},
+		func(request *plugin.CodeGeneratorRequest) {
+			request.Parameter = proto.String("include=foo.*.Page*,include=bar.**,table-name={{.MessageName | lower}}_v1")
+		})
+	testConvert(t, input,
+		map[string]string{
+			"foo/events/foo_events_page_view.schema": `[{ "name": "url", "type": "STRING", "mode": "NULLABLE" }]`,
+			"foo/events/clicks.schema":               `[{ "name": "x", "type": "INTEGER", "mode": "NULLABLE" }]`,
+		},
+		func(request *plugin.CodeGeneratorRequest) {
+			request.Parameter = proto.String("include=**.PageView,table-name={{.Package | identifier}}_{{.MessageName | snake}}")
+		})
+	testConvertError(t, input,
+		`Failed to convert foo/events.proto: cannot name the table of message foo.events.PageView: the table-name template gives "foo.events_page_view", which is not a valid table name`,
+		func(request *plugin.CodeGeneratorRequest) {
+			request.Parameter = proto.String("include=**.PageView,table-name={{.Package}}_{{.MessageName | snake}}")
+		})
+	testConvertError(t, input,
+		`invalid value "{{.File | base}}_{{.MessageName}}" for parameter table-name: template: table-name:1: function "base" not defined`,
+		func(request *plugin.CodeGeneratorRequest) {
+			request.Parameter = proto.String("include=**,table-name={{.File | base}}_{{.MessageName}}")
+		})
+}
+
+func TestIncludePatterns(t *testing.T) {
+	for _, test := range []struct {
+		pattern string
+		name    string
+		matches bool
+	}{
+		{`re:foo\.events\.(Page|Screen)View`, "foo.events.ScreenView", true},
+		{`re:foo\.events\.(Page|Screen)View`, "foo.events.ScreenViews", false},
+		{"re:.*View", "foo.events.PageView", true},
+		{"foo.events.*", "foo.events.PageView", true},
+		{"foo.events.*", "foo.events.v1.PageView", false},
+		{"foo.**", "foo.events.v1.PageView", true},
+		{"foo.*.Page?iew", "foo.events.PageView", true},
+		{"foo.events.PageView", "foo.events.PageViews", false},
+		{"foo.events.PageView", "fooXevents.PageView", false},
+	} {
+		re, err := includePattern(test.pattern)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if re.MatchString(test.name) != test.matches {
+			t.Errorf("pattern %s matches %s: %v, expected %v", test.pattern, test.name, !test.matches, test.matches)
+		}
+	}
+
+	if _, err := ParseParameters("include=re:foo("); err == nil || !strings.HasPrefix(err.Error(), `invalid value "re:foo(" for parameter include: error parsing regexp`) {
+		t.Errorf("ParseParameters() failed with %v, expected an invalid regular expression", err)
+	}
+}
+
+func TestSingleMessage(t *testing.T) {
+	input := `
+			file_to_generate: "foo/a.proto"
+			file_to_generate: "foo/b.proto"
+			proto_file <
+				name: "foo/a.proto"
+				package: "foo"
+				message_type <
+					name: "First"
+					field < name: "i" number: 1 type: TYPE_INT32 label: LABEL_OPTIONAL >
+				>
+				message_type <
+					name: "Second"
+					field < name: "my_field" number: 1 type: TYPE_STRING label: LABEL_OPTIONAL json_name: "myField" >
+					options < [gen_bq_schema.bigquery_opts] <table_name: "seconds" use_json_names: true> >
+				>
+			>
+			proto_file <
+				name: "foo/b.proto"
+				package: "foo"
+				dependency: "foo/a.proto"
+				message_type <
+					name: "Ref"
+					field < name: "second" number: 1 type: TYPE_MESSAGE label: LABEL_OPTIONAL type_name: ".foo.Second" >
+				>
+			>
+		`
+	ref := `[{
+		"name": "second", "type": "RECORD", "mode": "NULLABLE",
+		"fields": [{ "name": "my_field", "type": "STRING", "mode": "NULLABLE" }]
+	}]`
+	testConvert(t, input,
+		map[string]string{
+			"foo/seconds.schema": `[{ "name": "myField", "type": "STRING", "mode": "NULLABLE" }]`,
+			"foo/b.schema":       ref,
+		},
+		func(request *plugin.CodeGeneratorRequest) {
+			request.Parameter = proto.String("single-message")
+		})
+	testConvert(t, input,
+		map[string]string{
+			"foo/a.schema": `[{ "name": "i", "type": "INTEGER", "mode": "NULLABLE" }]`,
+		},
+		func(request *plugin.CodeGeneratorRequest) {
+			request.FileToGenerate = []string{"foo/a.proto"}
+			request.Parameter = proto.String("single-message,root-message=foo.First")
+		})
+	testConvertError(t, input,
+		"Failed to convert foo/b.proto: root message foo.First is not a top-level message of the file",
+		func(request *plugin.CodeGeneratorRequest) {
+			request.Parameter = proto.String("single-message,root-message=foo.First")
+		})
+	testConvertError(t, input,
+		"Failed to convert foo/a.proto: root message Frist is not a top-level message of the file",
+		func(request *plugin.CodeGeneratorRequest) {
+			request.FileToGenerate = []string{"foo/a.proto"}
+			request.Parameter = proto.String("single-message,root-message=Frist")
+		})
+	testConvertError(t, input,
+		"parameter root-message requires single-message",
+		func(request *plugin.CodeGeneratorRequest) {
+			request.Parameter = proto.String("root-message=foo.First")
+		})
+}
+
+func TestOutputPath(t *testing.T) {
+	input := `
+			file_to_generate: "protos/foo.proto"
+			proto_file <
+				name: "protos/foo.proto"
+				package: "example_package.v1"
+				message_type <
+					name: "FooProto"
+					field < name: "i" number: 1 type: TYPE_INT32 label: LABEL_OPTIONAL >
+					options < [gen_bq_schema.bigquery_opts] <table_name: "foo_table" labels < key: "team" value: "data" > > >
+				>
+				message_type <
+					name: "BarProto"
+					field < name: "i" number: 1 type: TYPE_INT32 label: LABEL_OPTIONAL >
+					options < [gen_bq_schema.bigquery_opts] <table_name: "bar_table"> >
+				>
+			>
+		`
+	schema := `[{ "name": "i", "type": "INTEGER", "mode": "NULLABLE" }]`
+	testConvert(t, input,
+		map[string]string{
+			"example_package/v1/foo_table.schema": schema,
+			"example_package/v1/foo_table.table.json": `{
+				"tableReference": { "tableId": "foo_table" },
+				"schema": { "fields": ` + schema + ` },
+				"labels": { "team": "data" }
+			}`,
+			"example_package/v1/bar_table.schema": schema,
+		})
+	testConvert(t, input,
+		map[string]string{
+			"protos/schemas/analytics/FooProto.sql": "CREATE TABLE IF NOT EXISTS `analytics.foo_table` (\n  `i` INT64\n)\nOPTIONS(\n  labels=[(\"team\", \"data\")]\n);",
+			"protos/schemas/analytics/BarProto.sql": "CREATE TABLE IF NOT EXISTS `analytics.bar_table` (\n  `i` INT64\n);",
+		},
+		func(request *plugin.CodeGeneratorRequest) {
+			request.Parameter = proto.String("format=ddl,dataset=analytics,output-path={{.FileDir}}/schemas/{{.Dataset}}/{{.MessageName}}.{{.Extension}}")
+		})
+
+	testConvertError(t, input,
+		"Failed to convert protos/foo.proto: output example_package.v1/tables.json of message example_package.v1.BarProto collides with that of message example_package.v1.FooProto",
+		func(request *plugin.CodeGeneratorRequest) {
+			request.Parameter = proto.String("format=ddl,output-path={{.Package}}/tables.json")
+		})
+	testConvertError(t, input,
+		"Failed to convert protos/foo.proto: message example_package.v1.FooProto generates several outputs at foo_table.json",
+		func(request *plugin.CodeGeneratorRequest) {
+			request.Parameter = proto.String("output-path={{.Table}}.json")
+		})
+	testConvertError(t, input,
+		"Failed to convert protos/foo.proto: output ../foo_table.schema of table foo_table is outside of the output directory",
+		func(request *plugin.CodeGeneratorRequest) {
+			request.Parameter = proto.String("output-path=../{{.Table}}.{{.Extension}}")
+		})
+	testConvertError(t, input,
+		"Failed to convert protos/foo.proto: output /schemas/foo_table.schema of table foo_table is an absolute path",
+		func(request *plugin.CodeGeneratorRequest) {
+			request.Parameter = proto.String("output-path=/schemas/{{.Table}}.{{.Extension}}")
+		})
+	testConvertError(t, input,
+		"Failed to convert protos/foo.proto: output \"schemas//foo_table.schema\" of table foo_table has an empty path element",
+		func(request *plugin.CodeGeneratorRequest) {
+			request.Parameter = proto.String("output-path=schemas//{{.Table}}.{{.Extension}}")
+		})
+	testConvertError(t, input,
+		"Failed to convert protos/foo.proto: output ./foo_table.schema of table foo_table is not normalized",
+		func(request *plugin.CodeGeneratorRequest) {
+			request.Parameter = proto.String("output-path=./{{.Table}}.{{.Extension}}")
+		})
+	testConvertError(t, input,
+		`Failed to convert protos/foo.proto: cannot name the output of table foo_table: template: output-path:1:10: executing "output-path" at <.Dataset>: error calling Dataset: parameter dataset is not set`,
+		func(request *plugin.CodeGeneratorRequest) {
+			request.Parameter = proto.String("output-path=schemas/{{.Dataset}}/{{.Table}}.json")
 		})
 }
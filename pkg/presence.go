@@ -0,0 +1,130 @@
+package pkg
+
+import (
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	descriptor "google.golang.org/protobuf/types/descriptorpb"
+)
+
+// fieldPresence is the field_presence feature of Protobuf Editions.
+type fieldPresence int32
+
+const (
+	presenceUnknown        fieldPresence = 0
+	presenceExplicit       fieldPresence = 1
+	presenceImplicit       fieldPresence = 2
+	presenceLegacyRequired fieldPresence = 3
+)
+
+// Field numbers of the features of Protobuf Editions, which the descriptors of the version of protobuf
+// this plugin is built with keep as unknown fields.
+const (
+	fileFeaturesNumber    protowire.Number = 50 // FileOptions.features
+	messageFeaturesNumber protowire.Number = 12 // MessageOptions.features
+	fieldFeaturesNumber   protowire.Number = 21 // FieldOptions.features
+	fieldPresenceNumber   protowire.Number = 1  // FeatureSet.field_presence
+)
+
+// columnMode returns the mode of the column of a field declared in the given message. Besides the label of
+// the field, it follows its presence: fields which cannot be told unset from their default value, like the
+// scalars of proto3 which are not optional, are REQUIRED with implicit-presence=required, and fields with the
+// LEGACY_REQUIRED presence of Editions are REQUIRED.
+func (g *generation) columnMode(msg *ProtoType, fieldProto *descriptor.FieldDescriptorProto) string {
+	if fieldProto.GetLabel() != descriptor.FieldDescriptorProto_LABEL_OPTIONAL {
+		return modeFromFieldLabel[fieldProto.GetLabel()]
+	}
+	switch g.presence(msg, fieldProto) {
+	case presenceLegacyRequired:
+		return "REQUIRED"
+	case presenceImplicit:
+		if g.params.ImplicitPresence == implicitPresenceRequired {
+			return "REQUIRED"
+		}
+	}
+	return "NULLABLE"
+}
+
+// presence returns the presence of a singular field declared in the given message.
+func (g *generation) presence(msg *ProtoType, fieldProto *descriptor.FieldDescriptorProto) fieldPresence {
+	// Members of oneofs, including proto3 optional fields, always have explicit presence.
+	if fieldProto.OneofIndex != nil {
+		return presenceExplicit
+	}
+
+	presence := presenceExplicit
+	switch msg.File.GetSyntax() {
+	case "proto3":
+		presence = presenceImplicit
+	case "editions":
+		presence = g.editionsPresence(msg, fieldProto)
+	}
+	// Messages cannot have implicit presence, but Editions can still make them required.
+	if IsRecordType(fieldProto) && presence != presenceLegacyRequired {
+		return presenceExplicit
+	}
+	return presence
+}
+
+// editionsPresence returns the field_presence feature of a field declared in the given message of a file
+// using Editions. Features are inherited from the enclosing messages and the file, and default to explicit
+// presence.
+func (g *generation) editionsPresence(msg *ProtoType, fieldProto *descriptor.FieldDescriptorProto) fieldPresence {
+	if presence := featurePresence(fieldProto.GetOptions(), fieldFeaturesNumber); presence != presenceUnknown {
+		return presence
+	}
+	for name := msg.Name; ; name = name[:strings.LastIndexByte(name, '.')] {
+		pt := g.locals.GetType(name)
+		if pt == nil {
+			break
+		}
+		if presence := featurePresence(pt.Type.GetOptions(), messageFeaturesNumber); presence != presenceUnknown {
+			return presence
+		}
+	}
+	if presence := featurePresence(msg.File.GetOptions(), fileFeaturesNumber); presence != presenceUnknown {
+		return presence
+	}
+	return presenceExplicit
+}
+
+// featurePresence returns the field_presence feature set in options, whose features are the field with the
+// given number, or presenceUnknown if it is not set.
+func featurePresence(options proto.Message, featuresNumber protowire.Number) fieldPresence {
+	if options == nil || !options.ProtoReflect().IsValid() {
+		return presenceUnknown
+	}
+	presence := presenceUnknown
+	forEachField(options.ProtoReflect().GetUnknown(), func(num protowire.Number, typ protowire.Type, value []byte) {
+		if num != featuresNumber || typ != protowire.BytesType {
+			return
+		}
+		features, _ := protowire.ConsumeBytes(value)
+		forEachField(features, func(num protowire.Number, typ protowire.Type, value []byte) {
+			if num == fieldPresenceNumber && typ == protowire.VarintType {
+				v, _ := protowire.ConsumeVarint(value)
+				presence = fieldPresence(v)
+			}
+		})
+	})
+	return presence
+}
+
+// forEachField calls fn with the number, the wire type and the encoded value of each field of a message
+// in the wire format, stopping at the first malformed field.
+func forEachField(b []byte, fn func(num protowire.Number, typ protowire.Type, value []byte)) {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return
+		}
+		b = b[n:]
+		m := protowire.ConsumeFieldValue(num, typ, b)
+		if m < 0 {
+			return
+		}
+		fn(num, typ, b[:m])
+		b = b[m:]
+	}
+}
@@ -0,0 +1,96 @@
+package pkg
+
+import (
+	"encoding/json"
+)
+
+// tableResource is a table in the shape of the BigQuery REST Table resource, as accepted by
+// tables.insert and tables.patch.
+type tableResource struct {
+	TableReference tableReference    `json:"tableReference"`
+	Description    string            `json:"description,omitempty"`
+	Labels         map[string]string `json:"labels,omitempty"`
+	Schema         struct {
+		Fields Schema `json:"fields"`
+	} `json:"schema"`
+	TimePartitioning       *timePartitioning  `json:"timePartitioning,omitempty"`
+	RangePartitioning      *rangePartitioning `json:"rangePartitioning,omitempty"`
+	RequirePartitionFilter bool               `json:"requirePartitionFilter,omitempty"`
+	Clustering             *clustering        `json:"clustering,omitempty"`
+	ExpirationTime         int64              `json:"expirationTime,omitempty,string"`
+}
+
+type tableReference struct {
+	ProjectID string `json:"projectId,omitempty"`
+	DatasetID string `json:"datasetId,omitempty"`
+	TableID   string `json:"tableId"`
+}
+
+type timePartitioning struct {
+	Type         string `json:"type"`
+	Field        string `json:"field,omitempty"`
+	ExpirationMs int64  `json:"expirationMs,omitempty,string"`
+}
+
+type rangePartitioning struct {
+	Field string `json:"field"`
+	Range struct {
+		Start    int64 `json:"start,string"`
+		End      int64 `json:"end,string"`
+		Interval int64 `json:"interval,string"`
+	} `json:"range"`
+}
+
+type clustering struct {
+	Fields []string `json:"fields"`
+}
+
+// hasResourceOptions reports whether the table has options which a JSON schema cannot express:
+// partitioning, clustering, labels or an expiration time.
+func (t *Table) hasResourceOptions() bool {
+	return t.Options.GetTimePartitioning() != nil || t.Options.GetRangePartitioning() != nil ||
+		t.Options.GetRequirePartitionFilter() || len(t.Options.GetClusteringFields()) > 0 ||
+		len(t.Options.GetLabels()) > 0 || t.Options.GetExpirationTime() != 0
+}
+
+// resource returns the REST resource of the table.
+func (t *Table) resource() *tableResource {
+	res := &tableResource{
+		TableReference: tableReference{
+			ProjectID: t.Project,
+			DatasetID: t.Dataset,
+			TableID:   t.Name,
+		},
+		Description:            t.Description,
+		Labels:                 t.Options.GetLabels(),
+		RequirePartitionFilter: t.Options.GetRequirePartitionFilter(),
+		ExpirationTime:         t.Options.GetExpirationTime(),
+	}
+	res.Schema.Fields = t.Schema
+	if tp := t.Options.GetTimePartitioning(); tp != nil {
+		res.TimePartitioning = &timePartitioning{
+			Type:         timePartitioningType(t),
+			Field:        tp.GetField(),
+			ExpirationMs: tp.GetExpirationMs(),
+		}
+	}
+	if rp := t.Options.GetRangePartitioning(); rp != nil {
+		res.RangePartitioning = &rangePartitioning{Field: rp.GetField()}
+		res.RangePartitioning.Range.Start = rp.GetStart()
+		res.RangePartitioning.Range.End = rp.GetEnd()
+		res.RangePartitioning.Range.Interval = rp.GetInterval()
+	}
+	if fields := t.Options.GetClusteringFields(); len(fields) > 0 {
+		res.Clustering = &clustering{Fields: fields}
+	}
+	return res
+}
+
+// renderTable renders a table as the JSON of its REST resource.
+func renderTable(table *Table) (string, error) {
+	res, err := json.MarshalIndent(table.resource(), "", " ")
+	if err != nil {
+		return "", err
+	}
+	return string(res), nil
+}
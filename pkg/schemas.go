@@ -2,8 +2,48 @@ package pkg
 
 import (
 	"fmt"
+	"regexp"
+
+	"github.com/GoogleCloudPlatform/protoc-gen-bq-schema/protos"
 )
 
+var (
+	// bigQueryTypes holds the column types accepted in BigQuery schemas, including legacy aliases.
+	bigQueryTypes = map[string]bool{
+		"STRING": true, "BYTES": true,
+		"INTEGER": true, "INT64": true,
+		"FLOAT": true, "FLOAT64": true, "NUMERIC": true, "BIGNUMERIC": true,
+		"BOOLEAN": true, "BOOL": true,
+		"TIMESTAMP": true, "DATE": true, "TIME": true, "DATETIME": true, "INTERVAL": true,
+		"GEOGRAPHY": true, "JSON": true,
+		"RECORD": true, "STRUCT": true,
+	}
+
+	bigQueryModes = map[string]bool{
+		"NULLABLE": true,
+		"REQUIRED": true,
+		"REPEATED": true,
+	}
+
+	fieldNameRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]{0,299}$`)
+	tableNameRe = regexp.MustCompile(`^[\p{L}\p{M}\p{N}\p{Pc}\p{Pd} ]+$`)
+)
+
+// maxTableNameLength is the maximum length of the name of a table, in bytes.
+const maxTableNameLength = 1024
+
+// isValidTableName reports whether name is a valid BigQuery table name: letters, marks, numbers,
+// connectors like underscores, dashes and spaces, and at most 1024 bytes long.
+func isValidTableName(name string) bool {
+	return len(name) <= maxTableNameLength && tableNameRe.MatchString(name)
+}
+
+// isValidFieldName reports whether name is a valid BigQuery column name: letters, numbers and
+// underscores, starting with a letter or an underscore, and at most 300 characters long.
+func isValidFieldName(name string) bool {
+	return fieldNameRe.MatchString(name)
+}
+
 type Schema []*Field
 
 type Field struct {
@@ -39,3 +79,27 @@ func NewBQField(name string, type_ string, mode string, description string, opts
 type PolicyTags struct {
 	Names []string `json:"names,omitempty"`
 }
+
+// Table describes the BigQuery table a message is stored in.
+type Table struct {
+	Project     string
+	Dataset     string
+	Name        string
+	Description string
+	Schema      Schema
+	// Previous is the schema previously generated for the table, or nil for new tables.
+	Previous Schema
+	Options  *protos.BigQueryMessageOptions
+}
+
+// QualifiedName returns the name of the table qualified with its dataset and project, when they are known.
+func (t *Table) QualifiedName() string {
+	name := t.Name
+	if t.Dataset != "" {
+		name = t.Dataset + "." + name
+		if t.Project != "" {
+			name = t.Project + "." + name
+		}
+	}
+	return name
+}
@@ -0,0 +1,135 @@
+package pkg
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+// templateFuncs are the functions available in the templates of table names and output paths.
+var templateFuncs = template.FuncMap{
+	"snake":      snakeCase,
+	"lower":      strings.ToLower,
+	"upper":      strings.ToUpper,
+	"identifier": identifier,
+}
+
+// defaultTableName names the tables of messages selected with the include parameter, unless set with
+// the table-name parameter.
+var defaultTableName = template.Must(parseTemplate("table-name", "{{.MessageName | snake}}"))
+
+// tableNameData is the data table name templates are executed with.
+type tableNameData struct {
+	// MessageName is the name of the message, e.g. "PageView".
+	MessageName string
+	// FullName is the fully-qualified name of the message, e.g. "foo.events.PageView".
+	FullName string
+	// Package is the package the table is generated in, e.g. "foo.events", as mapped with M parameters.
+	Package string
+	// File is the name of the proto file declaring the message, without its extension, e.g. "foo/events".
+	File string
+}
+
+// parseTemplate parses the template given with the parameter of the given name.
+func parseTemplate(name, text string) (*template.Template, error) {
+	return template.New(name).Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+}
+
+// includePattern compiles a pattern of the include parameter: a regular expression matching whole
+// fully-qualified message names after a "re:" prefix, or else a glob.
+func includePattern(pattern string) (*regexp.Regexp, error) {
+	if expr := strings.TrimPrefix(pattern, "re:"); expr != pattern {
+		if expr == "" {
+			return nil, fmt.Errorf("empty pattern")
+		}
+		return regexp.Compile("^(?:" + expr + ")$")
+	}
+	return globRegexp(pattern)
+}
+
+// globRegexp compiles a glob matching fully-qualified message names, where * matches any part of a
+// name between dots and ** matches any sequence of names.
+func globRegexp(glob string) (*regexp.Regexp, error) {
+	if glob == "" {
+		return nil, fmt.Errorf("empty pattern")
+	}
+	var re strings.Builder
+	re.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		switch {
+		case strings.HasPrefix(glob[i:], "**"):
+			re.WriteString(".*")
+			i++
+		case glob[i] == '*':
+			re.WriteString(`[^.]*`)
+		case glob[i] == '?':
+			re.WriteString(`[^.]`)
+		default:
+			re.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		}
+	}
+	re.WriteString("$")
+	return regexp.Compile(re.String())
+}
+
+// identifier replaces the characters of s other than letters, numbers and underscores with underscores,
+// e.g. "foo.events" with "foo_events".
+func identifier(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' {
+			return r
+		}
+		return '_'
+	}, s)
+}
+
+// snakeCase converts a name in camel case to snake case, e.g. "HTTPRequestLog" to "http_request_log".
+func snakeCase(s string) string {
+	runes := []rune(s)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) && i > 0 {
+			prev := runes[i-1]
+			nextIsLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextIsLower) {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+// includedTableName returns the name of the table of a message selected with the include parameter,
+// following the table-name template, or "" if the message is not selected.
+func (g *generation) includedTableName(msg *ProtoType) (string, error) {
+	fullName := strings.TrimPrefix(msg.Name, ".")
+	included := false
+	for _, re := range g.params.Include {
+		included = included || re.MatchString(fullName)
+	}
+	if !included {
+		return "", nil
+	}
+
+	tmpl := g.params.TableName
+	if tmpl == nil {
+		tmpl = defaultTableName
+	}
+	var name bytes.Buffer
+	if err := tmpl.Execute(&name, tableNameData{
+		MessageName: msg.Type.GetName(),
+		FullName:    fullName,
+		Package:     g.outputPackage(msg.File),
+		File:        strings.TrimSuffix(msg.File.GetName(), ".proto"),
+	}); err != nil {
+		return "", fmt.Errorf("cannot name the table of message %s: %v", fullName, err)
+	}
+	if !isValidTableName(name.String()) {
+		return "", fmt.Errorf("cannot name the table of message %s: the table-name template gives %q, which is not a valid table name", fullName, name.String())
+	}
+	return name.String(), nil
+}
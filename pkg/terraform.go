@@ -0,0 +1,97 @@
+package pkg
+
+import (
+	"encoding/json"
+	"regexp"
+)
+
+// terraformTable is a google_bigquery_table resource of the Terraform Google provider, in the JSON
+// syntax of Terraform.
+type terraformTable struct {
+	Project                string                   `json:"project,omitempty"`
+	DatasetID              string                   `json:"dataset_id"`
+	TableID                string                   `json:"table_id"`
+	Description            string                   `json:"description,omitempty"`
+	Labels                 map[string]string        `json:"labels,omitempty"`
+	ExpirationTime         int64                    `json:"expiration_time,omitempty"`
+	TimePartitioning       *terraformTimePartition  `json:"time_partitioning,omitempty"`
+	RangePartitioning      *terraformRangePartition `json:"range_partitioning,omitempty"`
+	RequirePartitionFilter bool                     `json:"require_partition_filter,omitempty"`
+	Clustering             []string                 `json:"clustering,omitempty"`
+	// Schema is the JSON schema of the table, which the provider takes as a string.
+	Schema string `json:"schema"`
+}
+
+type terraformTimePartition struct {
+	Type         string `json:"type"`
+	Field        string `json:"field,omitempty"`
+	ExpirationMs int64  `json:"expiration_ms,omitempty"`
+}
+
+type terraformRangePartition struct {
+	Field string `json:"field"`
+	Range struct {
+		Start    int64 `json:"start"`
+		End      int64 `json:"end"`
+		Interval int64 `json:"interval"`
+	} `json:"range"`
+}
+
+// terraformNameRe matches the characters which are not allowed in the names of Terraform resources.
+var terraformNameRe = regexp.MustCompile(`[^A-Za-z0-9_-]`)
+
+// terraformName returns the name of the Terraform resource of a table: the name of the table with the
+// characters Terraform does not allow replaced by underscores, and prefixed with an underscore unless it
+// starts with a letter or an underscore, as Terraform identifiers must.
+func terraformName(tableName string) string {
+	name := terraformNameRe.ReplaceAllString(tableName, "_")
+	if c := name[0]; c != '_' && !('A' <= c && c <= 'Z' || 'a' <= c && c <= 'z') {
+		name = "_" + name
+	}
+	return name
+}
+
+// renderTerraform renders a table as a Terraform JSON configuration declaring a google_bigquery_table
+// resource named after the table.
+func renderTerraform(table *Table) (string, error) {
+	schema, err := json.Marshal(table.Schema)
+	if err != nil {
+		return "", err
+	}
+	res := table.resource()
+	tf := &terraformTable{
+		Project:                table.Project,
+		DatasetID:              table.Dataset,
+		TableID:                table.Name,
+		Description:            table.Description,
+		Labels:                 res.Labels,
+		ExpirationTime:         res.ExpirationTime,
+		RequirePartitionFilter: res.RequirePartitionFilter,
+		Schema:                 string(schema),
+	}
+	if tp := res.TimePartitioning; tp != nil {
+		tf.TimePartitioning = &terraformTimePartition{Type: tp.Type, Field: tp.Field, ExpirationMs: tp.ExpirationMs}
+	}
+	if rp := res.RangePartitioning; rp != nil {
+		tf.RangePartitioning = &terraformRangePartition{Field: rp.Field}
+		tf.RangePartitioning.Range.Start = rp.Range.Start
+		tf.RangePartitioning.Range.End = rp.Range.End
+		tf.RangePartitioning.Range.Interval = rp.Range.Interval
+	}
+	if res.Clustering != nil {
+		tf.Clustering = res.Clustering.Fields
+	}
+
+	config := map[string]interface{}{
+		"resource": map[string]interface{}{
+			"google_bigquery_table": map[string]interface{}{
+				terraformName(table.Name): tf,
+			},
+		},
+	}
+	content, err := json.MarshalIndent(config, "", " ")
+	if err != nil {
+		return "", err
+	}
+	return string(content), nil
+}
@@ -0,0 +1,76 @@
+package pkg
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// maxNestingDepth is the maximum depth of RECORD columns in BigQuery.
+	maxNestingDepth = 15
+	// maxColumns is the maximum number of columns of a table, including nested fields.
+	maxColumns = 10000
+	// maxDescriptionLength is the maximum length of the description of a column, in characters.
+	maxDescriptionLength = 1024
+)
+
+// reservedPrefixes lists the prefixes BigQuery reserves for the names of columns, in upper case.
+var reservedPrefixes = []string{"_TABLE_", "_FILE_", "_PARTITION", "_ROW_TIMESTAMP", "__ROOT__", "_COLIDENTIFIER"}
+
+// validateSchema checks that BigQuery accepts the schema of a table generated from a message, and returns an
+// error listing every column it would reject, along with why.
+func validateSchema(msg *ProtoType, table *Table) error {
+	var problems []string
+	if !isValidTableName(table.Name) {
+		problems = append(problems, "invalid table name, expected letters, numbers, underscores, dashes and spaces")
+	}
+	columns := validateFields(table.Schema, "", 1, &problems)
+	if columns > maxColumns {
+		problems = append(problems, fmt.Sprintf("table has %d columns, at most %d are allowed", columns, maxColumns))
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid schema of table %s for message %s in %s:\n  %s",
+			table.Name, strings.TrimPrefix(msg.Name, "."), msg.File.GetName(), strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+// validateFields appends the problems of the columns of a schema nested at the given depth to problems,
+// prefixing their names with prefix, and returns the number of columns, including nested fields.
+func validateFields(schema Schema, prefix string, depth int, problems *[]string) int {
+	columns := len(schema)
+	names := make(map[string]string, len(schema))
+	for _, f := range schema {
+		path := prefix + f.Name
+		report := func(format string, args ...interface{}) {
+			*problems = append(*problems, "column "+path+": "+fmt.Sprintf(format, args...))
+		}
+
+		if !isValidFieldName(f.Name) {
+			report("invalid name, expected letters, numbers and underscores starting with a letter or an underscore")
+		}
+		for _, reserved := range reservedPrefixes {
+			if strings.HasPrefix(strings.ToUpper(f.Name), reserved) {
+				report("names starting with %s are reserved", reserved)
+			}
+		}
+		if other, ok := names[strings.ToLower(f.Name)]; ok {
+			report("duplicate name, conflicts with %s", prefix+other)
+		} else {
+			names[strings.ToLower(f.Name)] = f.Name
+		}
+		if n := len([]rune(f.Description)); n > maxDescriptionLength {
+			report("description is %d characters long, at most %d are allowed", n, maxDescriptionLength)
+		}
+		if sqlType(f) == "STRUCT" {
+			if len(f.Fields) == 0 {
+				report("RECORD has no fields")
+			}
+			if depth == maxNestingDepth+1 {
+				report("RECORD is nested more than %d levels deep", maxNestingDepth)
+			}
+			columns += validateFields(f.Fields, path+".", depth+1, problems)
+		}
+	}
+	return columns
+}
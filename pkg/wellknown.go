@@ -0,0 +1,57 @@
+package pkg
+
+import (
+	"strings"
+)
+
+// wellKnownType describes how a protobuf well-known message type is represented in BigQuery.
+// The representation follows the canonical JSON encoding of the type, which is what ends up
+// being loaded into BigQuery.
+type wellKnownType struct {
+	Type   string
+	Fields func() Schema
+}
+
+var wellKnownTypes = map[string]wellKnownType{
+	".google.protobuf.Timestamp": {Type: "TIMESTAMP"},
+	".google.protobuf.Duration":  {Type: "STRING"},
+	".google.protobuf.FieldMask": {Type: "STRING"},
+
+	".google.protobuf.DoubleValue": {Type: "FLOAT"},
+	".google.protobuf.FloatValue":  {Type: "FLOAT"},
+	".google.protobuf.Int64Value":  {Type: "INTEGER"},
+	".google.protobuf.UInt64Value": {Type: "INTEGER"},
+	".google.protobuf.Int32Value":  {Type: "INTEGER"},
+	".google.protobuf.UInt32Value": {Type: "INTEGER"},
+	".google.protobuf.BoolValue":   {Type: "BOOLEAN"},
+	".google.protobuf.StringValue": {Type: "STRING"},
+	".google.protobuf.BytesValue":  {Type: "BYTES"},
+
+	".google.protobuf.Struct":    {Type: "JSON"},
+	".google.protobuf.Value":     {Type: "JSON"},
+	".google.protobuf.ListValue": {Type: "JSON"},
+
+	".google.protobuf.Any": {Type: "RECORD", Fields: func() Schema {
+		return Schema{
+			NewBQField("type_url", "STRING", "NULLABLE", ""),
+			NewBQField("value", "BYTES", "NULLABLE", ""),
+		}
+	}},
+	// Empty carries no data, so it results in a RECORD without fields which is omitted.
+	".google.protobuf.Empty": {Type: "RECORD"},
+}
+
+// getWellKnownType returns the BigQuery representation of the given message type name,
+// if it names a well-known type.
+func getWellKnownType(typeName string) (wellKnownType, bool) {
+	wkt, ok := wellKnownTypes["."+strings.TrimPrefix(typeName, ".")]
+	return wkt, ok
+}
+
+// Schema returns the fields of the well-known type, or nil for scalar representations.
+func (w wellKnownType) Schema() Schema {
+	if w.Fields == nil {
+		return nil
+	}
+	return w.Fields()
+}
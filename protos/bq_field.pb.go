@@ -14,7 +14,7 @@
 
 // Code generated by protoc-gen-go. DO NOT EDIT.
 // versions:
-// 	protoc-gen-go v1.26.0
+// 	protoc-gen-go v1.27.1
 // 	protoc        v3.19.3
 // source: bq_field.proto
 
@@ -35,6 +35,64 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+// Formats of enum fields in BigQuery.
+type EnumFormat int32
+
+const (
+	// Use the format set with the "enums" plugin parameter. Defaults to
+	// ENUM_STRING.
+	EnumFormat_ENUM_FORMAT_UNSPECIFIED EnumFormat = 0
+	// The name of the enum value, as a STRING.
+	EnumFormat_ENUM_STRING EnumFormat = 1
+	// The number of the enum value, as an INTEGER.
+	EnumFormat_ENUM_INTEGER EnumFormat = 2
+	// A RECORD holding both the "name" and the "number" of the enum value.
+	EnumFormat_ENUM_NAME_AND_NUMBER EnumFormat = 3
+)
+
+// Enum value maps for EnumFormat.
+var (
+	EnumFormat_name = map[int32]string{
+		0: "ENUM_FORMAT_UNSPECIFIED",
+		1: "ENUM_STRING",
+		2: "ENUM_INTEGER",
+		3: "ENUM_NAME_AND_NUMBER",
+	}
+	EnumFormat_value = map[string]int32{
+		"ENUM_FORMAT_UNSPECIFIED": 0,
+		"ENUM_STRING":             1,
+		"ENUM_INTEGER":            2,
+		"ENUM_NAME_AND_NUMBER":    3,
+	}
+)
+
+func (x EnumFormat) Enum() *EnumFormat {
+	p := new(EnumFormat)
+	*p = x
+	return p
+}
+
+func (x EnumFormat) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (EnumFormat) Descriptor() protoreflect.EnumDescriptor {
+	return file_bq_field_proto_enumTypes[0].Descriptor()
+}
+
+func (EnumFormat) Type() protoreflect.EnumType {
+	return &file_bq_field_proto_enumTypes[0]
+}
+
+func (x EnumFormat) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use EnumFormat.Descriptor instead.
+func (EnumFormat) EnumDescriptor() ([]byte, []int) {
+	return file_bq_field_proto_rawDescGZIP(), []int{0}
+}
+
 // Message containing options related to BigQuery schema generation
 // and management via Protobuf.
 type BigQueryFieldOptions struct {
@@ -58,6 +116,11 @@ type BigQueryFieldOptions struct {
 	Name string `protobuf:"bytes,5,opt,name=name,proto3" json:"name,omitempty"`
 	// Optionally add PolicyTag for a field in BigQuery schema.
 	PolicyTags string `protobuf:"bytes,6,opt,name=policy_tags,json=policyTags,proto3" json:"policy_tags,omitempty"`
+	// Selects how an enum field is rendered, overriding the "enums" plugin
+	// parameter.
+	EnumFormat EnumFormat `protobuf:"varint,7,opt,name=enum_format,json=enumFormat,proto3,enum=gen_bq_schema.EnumFormat" json:"enum_format,omitempty"`
+	// Append the values allowed for an enum field to its description.
+	DescribeEnumValues bool `protobuf:"varint,8,opt,name=describe_enum_values,json=describeEnumValues,proto3" json:"describe_enum_values,omitempty"`
 }
 
 func (x *BigQueryFieldOptions) Reset() {
@@ -134,6 +197,20 @@ func (x *BigQueryFieldOptions) GetPolicyTags() string {
 	return ""
 }
 
+func (x *BigQueryFieldOptions) GetEnumFormat() EnumFormat {
+	if x != nil {
+		return x.EnumFormat
+	}
+	return EnumFormat_ENUM_FORMAT_UNSPECIFIED
+}
+
+func (x *BigQueryFieldOptions) GetDescribeEnumValues() bool {
+	if x != nil {
+		return x.DescribeEnumValues
+	}
+	return false
+}
+
 var file_bq_field_proto_extTypes = []protoimpl.ExtensionInfo{
 	{
 		ExtendedType:  (*descriptor.FieldOptions)(nil),
@@ -160,7 +237,7 @@ var file_bq_field_proto_rawDesc = []byte{
 	0x12, 0x0d, 0x67, 0x65, 0x6e, 0x5f, 0x62, 0x71, 0x5f, 0x73, 0x63, 0x68, 0x65, 0x6d, 0x61, 0x1a,
 	0x20, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66,
 	0x2f, 0x64, 0x65, 0x73, 0x63, 0x72, 0x69, 0x70, 0x74, 0x6f, 0x72, 0x2e, 0x70, 0x72, 0x6f, 0x74,
-	0x6f, 0x22, 0xc4, 0x01, 0x0a, 0x14, 0x42, 0x69, 0x67, 0x51, 0x75, 0x65, 0x72, 0x79, 0x46, 0x69,
+	0x6f, 0x22, 0xb2, 0x02, 0x0a, 0x14, 0x42, 0x69, 0x67, 0x51, 0x75, 0x65, 0x72, 0x79, 0x46, 0x69,
 	0x65, 0x6c, 0x64, 0x4f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x12, 0x18, 0x0a, 0x07, 0x72, 0x65,
 	0x71, 0x75, 0x69, 0x72, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x08, 0x52, 0x07, 0x72, 0x65, 0x71,
 	0x75, 0x69, 0x72, 0x65, 0x12, 0x23, 0x0a, 0x0d, 0x74, 0x79, 0x70, 0x65, 0x5f, 0x6f, 0x76, 0x65,
@@ -172,14 +249,31 @@ var file_bq_field_proto_rawDesc = []byte{
 	0x69, 0x6f, 0x6e, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28,
 	0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x1f, 0x0a, 0x0b, 0x70, 0x6f, 0x6c, 0x69, 0x63,
 	0x79, 0x5f, 0x74, 0x61, 0x67, 0x73, 0x18, 0x06, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x70, 0x6f,
-	0x6c, 0x69, 0x63, 0x79, 0x54, 0x61, 0x67, 0x73, 0x3a, 0x5f, 0x0a, 0x08, 0x62, 0x69, 0x67, 0x71,
-	0x75, 0x65, 0x72, 0x79, 0x12, 0x1d, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72,
-	0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x46, 0x69, 0x65, 0x6c, 0x64, 0x4f, 0x70, 0x74, 0x69,
-	0x6f, 0x6e, 0x73, 0x18, 0xfd, 0x07, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x23, 0x2e, 0x67, 0x65, 0x6e,
-	0x5f, 0x62, 0x71, 0x5f, 0x73, 0x63, 0x68, 0x65, 0x6d, 0x61, 0x2e, 0x42, 0x69, 0x67, 0x51, 0x75,
-	0x65, 0x72, 0x79, 0x46, 0x69, 0x65, 0x6c, 0x64, 0x4f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x52,
-	0x08, 0x62, 0x69, 0x67, 0x71, 0x75, 0x65, 0x72, 0x79, 0x42, 0x0b, 0x5a, 0x09, 0x2e, 0x2e, 0x2f,
-	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x73, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x6c, 0x69, 0x63, 0x79, 0x54, 0x61, 0x67, 0x73, 0x12, 0x3a, 0x0a, 0x0b, 0x65, 0x6e, 0x75, 0x6d,
+	0x5f, 0x66, 0x6f, 0x72, 0x6d, 0x61, 0x74, 0x18, 0x07, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x19, 0x2e,
+	0x67, 0x65, 0x6e, 0x5f, 0x62, 0x71, 0x5f, 0x73, 0x63, 0x68, 0x65, 0x6d, 0x61, 0x2e, 0x45, 0x6e,
+	0x75, 0x6d, 0x46, 0x6f, 0x72, 0x6d, 0x61, 0x74, 0x52, 0x0a, 0x65, 0x6e, 0x75, 0x6d, 0x46, 0x6f,
+	0x72, 0x6d, 0x61, 0x74, 0x12, 0x30, 0x0a, 0x14, 0x64, 0x65, 0x73, 0x63, 0x72, 0x69, 0x62, 0x65,
+	0x5f, 0x65, 0x6e, 0x75, 0x6d, 0x5f, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x73, 0x18, 0x08, 0x20, 0x01,
+	0x28, 0x08, 0x52, 0x12, 0x64, 0x65, 0x73, 0x63, 0x72, 0x69, 0x62, 0x65, 0x45, 0x6e, 0x75, 0x6d,
+	0x56, 0x61, 0x6c, 0x75, 0x65, 0x73, 0x2a, 0x66, 0x0a, 0x0a, 0x45, 0x6e, 0x75, 0x6d, 0x46, 0x6f,
+	0x72, 0x6d, 0x61, 0x74, 0x12, 0x1b, 0x0a, 0x17, 0x45, 0x4e, 0x55, 0x4d, 0x5f, 0x46, 0x4f, 0x52,
+	0x4d, 0x41, 0x54, 0x5f, 0x55, 0x4e, 0x53, 0x50, 0x45, 0x43, 0x49, 0x46, 0x49, 0x45, 0x44, 0x10,
+	0x00, 0x12, 0x0f, 0x0a, 0x0b, 0x45, 0x4e, 0x55, 0x4d, 0x5f, 0x53, 0x54, 0x52, 0x49, 0x4e, 0x47,
+	0x10, 0x01, 0x12, 0x10, 0x0a, 0x0c, 0x45, 0x4e, 0x55, 0x4d, 0x5f, 0x49, 0x4e, 0x54, 0x45, 0x47,
+	0x45, 0x52, 0x10, 0x02, 0x12, 0x18, 0x0a, 0x14, 0x45, 0x4e, 0x55, 0x4d, 0x5f, 0x4e, 0x41, 0x4d,
+	0x45, 0x5f, 0x41, 0x4e, 0x44, 0x5f, 0x4e, 0x55, 0x4d, 0x42, 0x45, 0x52, 0x10, 0x03, 0x3a, 0x5f,
+	0x0a, 0x08, 0x62, 0x69, 0x67, 0x71, 0x75, 0x65, 0x72, 0x79, 0x12, 0x1d, 0x2e, 0x67, 0x6f, 0x6f,
+	0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x46, 0x69, 0x65,
+	0x6c, 0x64, 0x4f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0xfd, 0x07, 0x20, 0x01, 0x28, 0x0b,
+	0x32, 0x23, 0x2e, 0x67, 0x65, 0x6e, 0x5f, 0x62, 0x71, 0x5f, 0x73, 0x63, 0x68, 0x65, 0x6d, 0x61,
+	0x2e, 0x42, 0x69, 0x67, 0x51, 0x75, 0x65, 0x72, 0x79, 0x46, 0x69, 0x65, 0x6c, 0x64, 0x4f, 0x70,
+	0x74, 0x69, 0x6f, 0x6e, 0x73, 0x52, 0x08, 0x62, 0x69, 0x67, 0x71, 0x75, 0x65, 0x72, 0x79, 0x42,
+	0x3c, 0x5a, 0x3a, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x47, 0x6f,
+	0x6f, 0x67, 0x6c, 0x65, 0x43, 0x6c, 0x6f, 0x75, 0x64, 0x50, 0x6c, 0x61, 0x74, 0x66, 0x6f, 0x72,
+	0x6d, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x63, 0x2d, 0x67, 0x65, 0x6e, 0x2d, 0x62, 0x71, 0x2d,
+	0x73, 0x63, 0x68, 0x65, 0x6d, 0x61, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x73, 0x62, 0x06, 0x70,
+	0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -194,19 +288,22 @@ func file_bq_field_proto_rawDescGZIP() []byte {
 	return file_bq_field_proto_rawDescData
 }
 
+var file_bq_field_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
 var file_bq_field_proto_msgTypes = make([]protoimpl.MessageInfo, 1)
 var file_bq_field_proto_goTypes = []interface{}{
-	(*BigQueryFieldOptions)(nil),    // 0: gen_bq_schema.BigQueryFieldOptions
-	(*descriptor.FieldOptions)(nil), // 1: google.protobuf.FieldOptions
+	(EnumFormat)(0),                 // 0: gen_bq_schema.EnumFormat
+	(*BigQueryFieldOptions)(nil),    // 1: gen_bq_schema.BigQueryFieldOptions
+	(*descriptor.FieldOptions)(nil), // 2: google.protobuf.FieldOptions
 }
 var file_bq_field_proto_depIdxs = []int32{
-	1, // 0: gen_bq_schema.bigquery:extendee -> google.protobuf.FieldOptions
-	0, // 1: gen_bq_schema.bigquery:type_name -> gen_bq_schema.BigQueryFieldOptions
-	2, // [2:2] is the sub-list for method output_type
-	2, // [2:2] is the sub-list for method input_type
-	1, // [1:2] is the sub-list for extension type_name
-	0, // [0:1] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	0, // 0: gen_bq_schema.BigQueryFieldOptions.enum_format:type_name -> gen_bq_schema.EnumFormat
+	2, // 1: gen_bq_schema.bigquery:extendee -> google.protobuf.FieldOptions
+	1, // 2: gen_bq_schema.bigquery:type_name -> gen_bq_schema.BigQueryFieldOptions
+	3, // [3:3] is the sub-list for method output_type
+	3, // [3:3] is the sub-list for method input_type
+	2, // [2:3] is the sub-list for extension type_name
+	1, // [1:2] is the sub-list for extension extendee
+	0, // [0:1] is the sub-list for field type_name
 }
 
 func init() { file_bq_field_proto_init() }
@@ -233,13 +330,14 @@ func file_bq_field_proto_init() {
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_bq_field_proto_rawDesc,
-			NumEnums:      0,
+			NumEnums:      1,
 			NumMessages:   1,
 			NumExtensions: 1,
 			NumServices:   0,
 		},
 		GoTypes:           file_bq_field_proto_goTypes,
 		DependencyIndexes: file_bq_field_proto_depIdxs,
+		EnumInfos:         file_bq_field_proto_enumTypes,
 		MessageInfos:      file_bq_field_proto_msgTypes,
 		ExtensionInfos:    file_bq_field_proto_extTypes,
 	}.Build()
@@ -14,7 +14,7 @@
 
 // Code generated by protoc-gen-go. DO NOT EDIT.
 // versions:
-// 	protoc-gen-go v1.26.0
+// 	protoc-gen-go v1.27.1
 // 	protoc        v3.19.3
 // source: bq_table.proto
 
@@ -35,6 +35,124 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+// Strategies for rendering the members of a oneof in BigQuery.
+// Synthetic oneofs of proto3 optional fields are always rendered as plain fields.
+type OneofStrategy int32
+
+const (
+	// Use the strategy set at a higher level: the file options, then the
+	// "oneofs" plugin parameter. Defaults to ONEOF_FLATTEN.
+	OneofStrategy_ONEOF_STRATEGY_UNSPECIFIED OneofStrategy = 0
+	// Each member of the oneof is a column of the message.
+	OneofStrategy_ONEOF_FLATTEN OneofStrategy = 1
+	// The members of the oneof are wrapped into a RECORD named after the oneof.
+	OneofStrategy_ONEOF_WRAP OneofStrategy = 2
+	// Each member of the oneof is a column of the message, preceded by a STRING
+	// column named after the oneof which holds the name of the member that is set.
+	OneofStrategy_ONEOF_DISCRIMINATOR OneofStrategy = 3
+)
+
+// Enum value maps for OneofStrategy.
+var (
+	OneofStrategy_name = map[int32]string{
+		0: "ONEOF_STRATEGY_UNSPECIFIED",
+		1: "ONEOF_FLATTEN",
+		2: "ONEOF_WRAP",
+		3: "ONEOF_DISCRIMINATOR",
+	}
+	OneofStrategy_value = map[string]int32{
+		"ONEOF_STRATEGY_UNSPECIFIED": 0,
+		"ONEOF_FLATTEN":              1,
+		"ONEOF_WRAP":                 2,
+		"ONEOF_DISCRIMINATOR":        3,
+	}
+)
+
+func (x OneofStrategy) Enum() *OneofStrategy {
+	p := new(OneofStrategy)
+	*p = x
+	return p
+}
+
+func (x OneofStrategy) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (OneofStrategy) Descriptor() protoreflect.EnumDescriptor {
+	return file_bq_table_proto_enumTypes[0].Descriptor()
+}
+
+func (OneofStrategy) Type() protoreflect.EnumType {
+	return &file_bq_table_proto_enumTypes[0]
+}
+
+func (x OneofStrategy) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use OneofStrategy.Descriptor instead.
+func (OneofStrategy) EnumDescriptor() ([]byte, []int) {
+	return file_bq_table_proto_rawDescGZIP(), []int{0}
+}
+
+// Renderings of recursive fields once the recursion depth is reached.
+type RecursionFallback int32
+
+const (
+	// Use the fallback set with the "recursion-fallback" plugin parameter.
+	// Defaults to RECURSION_OMIT.
+	RecursionFallback_RECURSION_FALLBACK_UNSPECIFIED RecursionFallback = 0
+	// The field is omitted.
+	RecursionFallback_RECURSION_OMIT RecursionFallback = 1
+	// The field is a JSON column holding the rest of the tree.
+	RecursionFallback_RECURSION_JSON RecursionFallback = 2
+	// The field is a STRING column holding the rest of the tree.
+	RecursionFallback_RECURSION_STRING RecursionFallback = 3
+)
+
+// Enum value maps for RecursionFallback.
+var (
+	RecursionFallback_name = map[int32]string{
+		0: "RECURSION_FALLBACK_UNSPECIFIED",
+		1: "RECURSION_OMIT",
+		2: "RECURSION_JSON",
+		3: "RECURSION_STRING",
+	}
+	RecursionFallback_value = map[string]int32{
+		"RECURSION_FALLBACK_UNSPECIFIED": 0,
+		"RECURSION_OMIT":                 1,
+		"RECURSION_JSON":                 2,
+		"RECURSION_STRING":               3,
+	}
+)
+
+func (x RecursionFallback) Enum() *RecursionFallback {
+	p := new(RecursionFallback)
+	*p = x
+	return p
+}
+
+func (x RecursionFallback) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (RecursionFallback) Descriptor() protoreflect.EnumDescriptor {
+	return file_bq_table_proto_enumTypes[1].Descriptor()
+}
+
+func (RecursionFallback) Type() protoreflect.EnumType {
+	return &file_bq_table_proto_enumTypes[1]
+}
+
+func (x RecursionFallback) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use RecursionFallback.Descriptor instead.
+func (RecursionFallback) EnumDescriptor() ([]byte, []int) {
+	return file_bq_table_proto_rawDescGZIP(), []int{1}
+}
+
 type BigQueryMessageOptions struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -52,6 +170,31 @@ type BigQueryMessageOptions struct {
 	// or "<field name>:RECORD:<protobuf type>" for message types.
 	// "NULLABLE" by default, different mode may be set via optional suffix ":<mode>"
 	ExtraFields []string `protobuf:"bytes,3,rep,name=extra_fields,json=extraFields,proto3" json:"extra_fields,omitempty"`
+	// Selects how the oneofs declared in the message are rendered.
+	OneofStrategy OneofStrategy `protobuf:"varint,4,opt,name=oneof_strategy,json=oneofStrategy,proto3,enum=gen_bq_schema.OneofStrategy" json:"oneof_strategy,omitempty"`
+	// Number of times the message is expanded within itself when it is
+	// recursive, e.g. the number of levels of replies of a comment thread.
+	// Overrides the "recursion-depth" plugin parameter, defaults to 0.
+	RecursionDepth int32 `protobuf:"varint,5,opt,name=recursion_depth,json=recursionDepth,proto3" json:"recursion_depth,omitempty"`
+	// Selects how fields of the message are rendered once the recursion depth
+	// is reached.
+	RecursionFallback RecursionFallback `protobuf:"varint,6,opt,name=recursion_fallback,json=recursionFallback,proto3,enum=gen_bq_schema.RecursionFallback" json:"recursion_fallback,omitempty"`
+	// Description of the table. Defaults to the comment of the message.
+	Description string `protobuf:"bytes,7,opt,name=description,proto3" json:"description,omitempty"`
+	// Partitions the table by time, either by a column or by ingestion time.
+	TimePartitioning *TimePartitioning `protobuf:"bytes,8,opt,name=time_partitioning,json=timePartitioning,proto3" json:"time_partitioning,omitempty"`
+	// Partitions the table by ranges of an integer column.
+	RangePartitioning *RangePartitioning `protobuf:"bytes,9,opt,name=range_partitioning,json=rangePartitioning,proto3" json:"range_partitioning,omitempty"`
+	// If true, queries over the table must filter on the partitioning column.
+	RequirePartitionFilter bool `protobuf:"varint,10,opt,name=require_partition_filter,json=requirePartitionFilter,proto3" json:"require_partition_filter,omitempty"`
+	// Columns the table is clustered by, in order of precedence. At most 4
+	// top-level columns.
+	ClusteringFields []string `protobuf:"bytes,11,rep,name=clustering_fields,json=clusteringFields,proto3" json:"clustering_fields,omitempty"`
+	// Labels of the table.
+	Labels map[string]string `protobuf:"bytes,12,rep,name=labels,proto3" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	// Time when the table expires, in milliseconds since the epoch. The table
+	// never expires when unset.
+	ExpirationTime int64 `protobuf:"varint,13,opt,name=expiration_time,json=expirationTime,proto3" json:"expiration_time,omitempty"`
 }
 
 func (x *BigQueryMessageOptions) Reset() {
@@ -107,6 +250,269 @@ func (x *BigQueryMessageOptions) GetExtraFields() []string {
 	return nil
 }
 
+func (x *BigQueryMessageOptions) GetOneofStrategy() OneofStrategy {
+	if x != nil {
+		return x.OneofStrategy
+	}
+	return OneofStrategy_ONEOF_STRATEGY_UNSPECIFIED
+}
+
+func (x *BigQueryMessageOptions) GetRecursionDepth() int32 {
+	if x != nil {
+		return x.RecursionDepth
+	}
+	return 0
+}
+
+func (x *BigQueryMessageOptions) GetRecursionFallback() RecursionFallback {
+	if x != nil {
+		return x.RecursionFallback
+	}
+	return RecursionFallback_RECURSION_FALLBACK_UNSPECIFIED
+}
+
+func (x *BigQueryMessageOptions) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *BigQueryMessageOptions) GetTimePartitioning() *TimePartitioning {
+	if x != nil {
+		return x.TimePartitioning
+	}
+	return nil
+}
+
+func (x *BigQueryMessageOptions) GetRangePartitioning() *RangePartitioning {
+	if x != nil {
+		return x.RangePartitioning
+	}
+	return nil
+}
+
+func (x *BigQueryMessageOptions) GetRequirePartitionFilter() bool {
+	if x != nil {
+		return x.RequirePartitionFilter
+	}
+	return false
+}
+
+func (x *BigQueryMessageOptions) GetClusteringFields() []string {
+	if x != nil {
+		return x.ClusteringFields
+	}
+	return nil
+}
+
+func (x *BigQueryMessageOptions) GetLabels() map[string]string {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
+
+func (x *BigQueryMessageOptions) GetExpirationTime() int64 {
+	if x != nil {
+		return x.ExpirationTime
+	}
+	return 0
+}
+
+// Time-unit column or ingestion-time partitioning of a table.
+type TimePartitioning struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Granularity of the partitions: DAY (the default), HOUR, MONTH or YEAR.
+	Type string `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`
+	// Top-level TIMESTAMP, DATE or DATETIME column the table is partitioned by.
+	// The table is partitioned by ingestion time when empty.
+	Field string `protobuf:"bytes,2,opt,name=field,proto3" json:"field,omitempty"`
+	// Number of milliseconds to keep the data of a partition for.
+	ExpirationMs int64 `protobuf:"varint,3,opt,name=expiration_ms,json=expirationMs,proto3" json:"expiration_ms,omitempty"`
+}
+
+func (x *TimePartitioning) Reset() {
+	*x = TimePartitioning{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_bq_table_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *TimePartitioning) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TimePartitioning) ProtoMessage() {}
+
+func (x *TimePartitioning) ProtoReflect() protoreflect.Message {
+	mi := &file_bq_table_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TimePartitioning.ProtoReflect.Descriptor instead.
+func (*TimePartitioning) Descriptor() ([]byte, []int) {
+	return file_bq_table_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *TimePartitioning) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *TimePartitioning) GetField() string {
+	if x != nil {
+		return x.Field
+	}
+	return ""
+}
+
+func (x *TimePartitioning) GetExpirationMs() int64 {
+	if x != nil {
+		return x.ExpirationMs
+	}
+	return 0
+}
+
+// Integer-range partitioning of a table.
+type RangePartitioning struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Top-level INTEGER column the table is partitioned by.
+	Field string `protobuf:"bytes,1,opt,name=field,proto3" json:"field,omitempty"`
+	// Start of the first partition, inclusive.
+	Start int64 `protobuf:"varint,2,opt,name=start,proto3" json:"start,omitempty"`
+	// End of the last partition, exclusive.
+	End int64 `protobuf:"varint,3,opt,name=end,proto3" json:"end,omitempty"`
+	// Width of each partition.
+	Interval int64 `protobuf:"varint,4,opt,name=interval,proto3" json:"interval,omitempty"`
+}
+
+func (x *RangePartitioning) Reset() {
+	*x = RangePartitioning{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_bq_table_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *RangePartitioning) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RangePartitioning) ProtoMessage() {}
+
+func (x *RangePartitioning) ProtoReflect() protoreflect.Message {
+	mi := &file_bq_table_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RangePartitioning.ProtoReflect.Descriptor instead.
+func (*RangePartitioning) Descriptor() ([]byte, []int) {
+	return file_bq_table_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *RangePartitioning) GetField() string {
+	if x != nil {
+		return x.Field
+	}
+	return ""
+}
+
+func (x *RangePartitioning) GetStart() int64 {
+	if x != nil {
+		return x.Start
+	}
+	return 0
+}
+
+func (x *RangePartitioning) GetEnd() int64 {
+	if x != nil {
+		return x.End
+	}
+	return 0
+}
+
+func (x *RangePartitioning) GetInterval() int64 {
+	if x != nil {
+		return x.Interval
+	}
+	return 0
+}
+
+type BigQueryFileOptions struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Selects how the oneofs declared in the messages of the file are rendered,
+	// unless overridden by the message options.
+	OneofStrategy OneofStrategy `protobuf:"varint,1,opt,name=oneof_strategy,json=oneofStrategy,proto3,enum=gen_bq_schema.OneofStrategy" json:"oneof_strategy,omitempty"`
+}
+
+func (x *BigQueryFileOptions) Reset() {
+	*x = BigQueryFileOptions{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_bq_table_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *BigQueryFileOptions) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BigQueryFileOptions) ProtoMessage() {}
+
+func (x *BigQueryFileOptions) ProtoReflect() protoreflect.Message {
+	mi := &file_bq_table_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BigQueryFileOptions.ProtoReflect.Descriptor instead.
+func (*BigQueryFileOptions) Descriptor() ([]byte, []int) {
+	return file_bq_table_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *BigQueryFileOptions) GetOneofStrategy() OneofStrategy {
+	if x != nil {
+		return x.OneofStrategy
+	}
+	return OneofStrategy_ONEOF_STRATEGY_UNSPECIFIED
+}
+
 var file_bq_table_proto_extTypes = []protoimpl.ExtensionInfo{
 	{
 		ExtendedType:  (*descriptor.MessageOptions)(nil),
@@ -116,6 +522,14 @@ var file_bq_table_proto_extTypes = []protoimpl.ExtensionInfo{
 		Tag:           "bytes,1021,opt,name=bigquery_opts",
 		Filename:      "bq_table.proto",
 	},
+	{
+		ExtendedType:  (*descriptor.FileOptions)(nil),
+		ExtensionType: (*BigQueryFileOptions)(nil),
+		Field:         1021,
+		Name:          "gen_bq_schema.bigquery_file_opts",
+		Tag:           "bytes,1021,opt,name=bigquery_file_opts",
+		Filename:      "bq_table.proto",
+	},
 }
 
 // Extension fields to descriptor.MessageOptions.
@@ -129,6 +543,14 @@ var (
 	E_BigqueryOpts = &file_bq_table_proto_extTypes[0]
 )
 
+// Extension fields to descriptor.FileOptions.
+var (
+	// BigQuery schema generation options applying to all messages of a file.
+	//
+	// optional gen_bq_schema.BigQueryFileOptions bigquery_file_opts = 1021;
+	E_BigqueryFileOpts = &file_bq_table_proto_extTypes[1]
+)
+
 var File_bq_table_proto protoreflect.FileDescriptor
 
 var file_bq_table_proto_rawDesc = []byte{
@@ -136,7 +558,7 @@ var file_bq_table_proto_rawDesc = []byte{
 	0x12, 0x0d, 0x67, 0x65, 0x6e, 0x5f, 0x62, 0x71, 0x5f, 0x73, 0x63, 0x68, 0x65, 0x6d, 0x61, 0x1a,
 	0x20, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66,
 	0x2f, 0x64, 0x65, 0x73, 0x63, 0x72, 0x69, 0x70, 0x74, 0x6f, 0x72, 0x2e, 0x70, 0x72, 0x6f, 0x74,
-	0x6f, 0x22, 0x80, 0x01, 0x0a, 0x16, 0x42, 0x69, 0x67, 0x51, 0x75, 0x65, 0x72, 0x79, 0x4d, 0x65,
+	0x6f, 0x22, 0x96, 0x06, 0x0a, 0x16, 0x42, 0x69, 0x67, 0x51, 0x75, 0x65, 0x72, 0x79, 0x4d, 0x65,
 	0x73, 0x73, 0x61, 0x67, 0x65, 0x4f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x12, 0x1d, 0x0a, 0x0a,
 	0x74, 0x61, 0x62, 0x6c, 0x65, 0x5f, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
 	0x52, 0x09, 0x74, 0x61, 0x62, 0x6c, 0x65, 0x4e, 0x61, 0x6d, 0x65, 0x12, 0x24, 0x0a, 0x0e, 0x75,
@@ -144,15 +566,100 @@ var file_bq_table_proto_rawDesc = []byte{
 	0x01, 0x28, 0x08, 0x52, 0x0c, 0x75, 0x73, 0x65, 0x4a, 0x73, 0x6f, 0x6e, 0x4e, 0x61, 0x6d, 0x65,
 	0x73, 0x12, 0x21, 0x0a, 0x0c, 0x65, 0x78, 0x74, 0x72, 0x61, 0x5f, 0x66, 0x69, 0x65, 0x6c, 0x64,
 	0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x09, 0x52, 0x0b, 0x65, 0x78, 0x74, 0x72, 0x61, 0x46, 0x69,
-	0x65, 0x6c, 0x64, 0x73, 0x3a, 0x6c, 0x0a, 0x0d, 0x62, 0x69, 0x67, 0x71, 0x75, 0x65, 0x72, 0x79,
-	0x5f, 0x6f, 0x70, 0x74, 0x73, 0x12, 0x1f, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70,
-	0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x4d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x4f,
-	0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0xfd, 0x07, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x25, 0x2e,
-	0x67, 0x65, 0x6e, 0x5f, 0x62, 0x71, 0x5f, 0x73, 0x63, 0x68, 0x65, 0x6d, 0x61, 0x2e, 0x42, 0x69,
-	0x67, 0x51, 0x75, 0x65, 0x72, 0x79, 0x4d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x4f, 0x70, 0x74,
-	0x69, 0x6f, 0x6e, 0x73, 0x52, 0x0c, 0x62, 0x69, 0x67, 0x71, 0x75, 0x65, 0x72, 0x79, 0x4f, 0x70,
-	0x74, 0x73, 0x42, 0x0b, 0x5a, 0x09, 0x2e, 0x2e, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x73, 0x62,
-	0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x65, 0x6c, 0x64, 0x73, 0x12, 0x43, 0x0a, 0x0e, 0x6f, 0x6e, 0x65, 0x6f, 0x66, 0x5f, 0x73, 0x74,
+	0x72, 0x61, 0x74, 0x65, 0x67, 0x79, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x1c, 0x2e, 0x67,
+	0x65, 0x6e, 0x5f, 0x62, 0x71, 0x5f, 0x73, 0x63, 0x68, 0x65, 0x6d, 0x61, 0x2e, 0x4f, 0x6e, 0x65,
+	0x6f, 0x66, 0x53, 0x74, 0x72, 0x61, 0x74, 0x65, 0x67, 0x79, 0x52, 0x0d, 0x6f, 0x6e, 0x65, 0x6f,
+	0x66, 0x53, 0x74, 0x72, 0x61, 0x74, 0x65, 0x67, 0x79, 0x12, 0x27, 0x0a, 0x0f, 0x72, 0x65, 0x63,
+	0x75, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x5f, 0x64, 0x65, 0x70, 0x74, 0x68, 0x18, 0x05, 0x20, 0x01,
+	0x28, 0x05, 0x52, 0x0e, 0x72, 0x65, 0x63, 0x75, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x44, 0x65, 0x70,
+	0x74, 0x68, 0x12, 0x4f, 0x0a, 0x12, 0x72, 0x65, 0x63, 0x75, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x5f,
+	0x66, 0x61, 0x6c, 0x6c, 0x62, 0x61, 0x63, 0x6b, 0x18, 0x06, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x20,
+	0x2e, 0x67, 0x65, 0x6e, 0x5f, 0x62, 0x71, 0x5f, 0x73, 0x63, 0x68, 0x65, 0x6d, 0x61, 0x2e, 0x52,
+	0x65, 0x63, 0x75, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x46, 0x61, 0x6c, 0x6c, 0x62, 0x61, 0x63, 0x6b,
+	0x52, 0x11, 0x72, 0x65, 0x63, 0x75, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x46, 0x61, 0x6c, 0x6c, 0x62,
+	0x61, 0x63, 0x6b, 0x12, 0x20, 0x0a, 0x0b, 0x64, 0x65, 0x73, 0x63, 0x72, 0x69, 0x70, 0x74, 0x69,
+	0x6f, 0x6e, 0x18, 0x07, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x64, 0x65, 0x73, 0x63, 0x72, 0x69,
+	0x70, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x4c, 0x0a, 0x11, 0x74, 0x69, 0x6d, 0x65, 0x5f, 0x70, 0x61,
+	0x72, 0x74, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x69, 0x6e, 0x67, 0x18, 0x08, 0x20, 0x01, 0x28, 0x0b,
+	0x32, 0x1f, 0x2e, 0x67, 0x65, 0x6e, 0x5f, 0x62, 0x71, 0x5f, 0x73, 0x63, 0x68, 0x65, 0x6d, 0x61,
+	0x2e, 0x54, 0x69, 0x6d, 0x65, 0x50, 0x61, 0x72, 0x74, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x69, 0x6e,
+	0x67, 0x52, 0x10, 0x74, 0x69, 0x6d, 0x65, 0x50, 0x61, 0x72, 0x74, 0x69, 0x74, 0x69, 0x6f, 0x6e,
+	0x69, 0x6e, 0x67, 0x12, 0x4f, 0x0a, 0x12, 0x72, 0x61, 0x6e, 0x67, 0x65, 0x5f, 0x70, 0x61, 0x72,
+	0x74, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x69, 0x6e, 0x67, 0x18, 0x09, 0x20, 0x01, 0x28, 0x0b, 0x32,
+	0x20, 0x2e, 0x67, 0x65, 0x6e, 0x5f, 0x62, 0x71, 0x5f, 0x73, 0x63, 0x68, 0x65, 0x6d, 0x61, 0x2e,
+	0x52, 0x61, 0x6e, 0x67, 0x65, 0x50, 0x61, 0x72, 0x74, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x69, 0x6e,
+	0x67, 0x52, 0x11, 0x72, 0x61, 0x6e, 0x67, 0x65, 0x50, 0x61, 0x72, 0x74, 0x69, 0x74, 0x69, 0x6f,
+	0x6e, 0x69, 0x6e, 0x67, 0x12, 0x38, 0x0a, 0x18, 0x72, 0x65, 0x71, 0x75, 0x69, 0x72, 0x65, 0x5f,
+	0x70, 0x61, 0x72, 0x74, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x5f, 0x66, 0x69, 0x6c, 0x74, 0x65, 0x72,
+	0x18, 0x0a, 0x20, 0x01, 0x28, 0x08, 0x52, 0x16, 0x72, 0x65, 0x71, 0x75, 0x69, 0x72, 0x65, 0x50,
+	0x61, 0x72, 0x74, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x46, 0x69, 0x6c, 0x74, 0x65, 0x72, 0x12, 0x2b,
+	0x0a, 0x11, 0x63, 0x6c, 0x75, 0x73, 0x74, 0x65, 0x72, 0x69, 0x6e, 0x67, 0x5f, 0x66, 0x69, 0x65,
+	0x6c, 0x64, 0x73, 0x18, 0x0b, 0x20, 0x03, 0x28, 0x09, 0x52, 0x10, 0x63, 0x6c, 0x75, 0x73, 0x74,
+	0x65, 0x72, 0x69, 0x6e, 0x67, 0x46, 0x69, 0x65, 0x6c, 0x64, 0x73, 0x12, 0x49, 0x0a, 0x06, 0x6c,
+	0x61, 0x62, 0x65, 0x6c, 0x73, 0x18, 0x0c, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x31, 0x2e, 0x67, 0x65,
+	0x6e, 0x5f, 0x62, 0x71, 0x5f, 0x73, 0x63, 0x68, 0x65, 0x6d, 0x61, 0x2e, 0x42, 0x69, 0x67, 0x51,
+	0x75, 0x65, 0x72, 0x79, 0x4d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x4f, 0x70, 0x74, 0x69, 0x6f,
+	0x6e, 0x73, 0x2e, 0x4c, 0x61, 0x62, 0x65, 0x6c, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x06,
+	0x6c, 0x61, 0x62, 0x65, 0x6c, 0x73, 0x12, 0x27, 0x0a, 0x0f, 0x65, 0x78, 0x70, 0x69, 0x72, 0x61,
+	0x74, 0x69, 0x6f, 0x6e, 0x5f, 0x74, 0x69, 0x6d, 0x65, 0x18, 0x0d, 0x20, 0x01, 0x28, 0x03, 0x52,
+	0x0e, 0x65, 0x78, 0x70, 0x69, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x54, 0x69, 0x6d, 0x65, 0x1a,
+	0x39, 0x0a, 0x0b, 0x4c, 0x61, 0x62, 0x65, 0x6c, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10,
+	0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79,
+	0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x22, 0x61, 0x0a, 0x10, 0x54, 0x69,
+	0x6d, 0x65, 0x50, 0x61, 0x72, 0x74, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x69, 0x6e, 0x67, 0x12, 0x12,
+	0x0a, 0x04, 0x74, 0x79, 0x70, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x74, 0x79,
+	0x70, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x66, 0x69, 0x65, 0x6c, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x05, 0x66, 0x69, 0x65, 0x6c, 0x64, 0x12, 0x23, 0x0a, 0x0d, 0x65, 0x78, 0x70, 0x69,
+	0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x5f, 0x6d, 0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x03, 0x52,
+	0x0c, 0x65, 0x78, 0x70, 0x69, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x4d, 0x73, 0x22, 0x6d, 0x0a,
+	0x11, 0x52, 0x61, 0x6e, 0x67, 0x65, 0x50, 0x61, 0x72, 0x74, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x69,
+	0x6e, 0x67, 0x12, 0x14, 0x0a, 0x05, 0x66, 0x69, 0x65, 0x6c, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x05, 0x66, 0x69, 0x65, 0x6c, 0x64, 0x12, 0x14, 0x0a, 0x05, 0x73, 0x74, 0x61, 0x72,
+	0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x05, 0x73, 0x74, 0x61, 0x72, 0x74, 0x12, 0x10,
+	0x0a, 0x03, 0x65, 0x6e, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x03, 0x52, 0x03, 0x65, 0x6e, 0x64,
+	0x12, 0x1a, 0x0a, 0x08, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x76, 0x61, 0x6c, 0x18, 0x04, 0x20, 0x01,
+	0x28, 0x03, 0x52, 0x08, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x76, 0x61, 0x6c, 0x22, 0x5a, 0x0a, 0x13,
+	0x42, 0x69, 0x67, 0x51, 0x75, 0x65, 0x72, 0x79, 0x46, 0x69, 0x6c, 0x65, 0x4f, 0x70, 0x74, 0x69,
+	0x6f, 0x6e, 0x73, 0x12, 0x43, 0x0a, 0x0e, 0x6f, 0x6e, 0x65, 0x6f, 0x66, 0x5f, 0x73, 0x74, 0x72,
+	0x61, 0x74, 0x65, 0x67, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x1c, 0x2e, 0x67, 0x65,
+	0x6e, 0x5f, 0x62, 0x71, 0x5f, 0x73, 0x63, 0x68, 0x65, 0x6d, 0x61, 0x2e, 0x4f, 0x6e, 0x65, 0x6f,
+	0x66, 0x53, 0x74, 0x72, 0x61, 0x74, 0x65, 0x67, 0x79, 0x52, 0x0d, 0x6f, 0x6e, 0x65, 0x6f, 0x66,
+	0x53, 0x74, 0x72, 0x61, 0x74, 0x65, 0x67, 0x79, 0x2a, 0x6b, 0x0a, 0x0d, 0x4f, 0x6e, 0x65, 0x6f,
+	0x66, 0x53, 0x74, 0x72, 0x61, 0x74, 0x65, 0x67, 0x79, 0x12, 0x1e, 0x0a, 0x1a, 0x4f, 0x4e, 0x45,
+	0x4f, 0x46, 0x5f, 0x53, 0x54, 0x52, 0x41, 0x54, 0x45, 0x47, 0x59, 0x5f, 0x55, 0x4e, 0x53, 0x50,
+	0x45, 0x43, 0x49, 0x46, 0x49, 0x45, 0x44, 0x10, 0x00, 0x12, 0x11, 0x0a, 0x0d, 0x4f, 0x4e, 0x45,
+	0x4f, 0x46, 0x5f, 0x46, 0x4c, 0x41, 0x54, 0x54, 0x45, 0x4e, 0x10, 0x01, 0x12, 0x0e, 0x0a, 0x0a,
+	0x4f, 0x4e, 0x45, 0x4f, 0x46, 0x5f, 0x57, 0x52, 0x41, 0x50, 0x10, 0x02, 0x12, 0x17, 0x0a, 0x13,
+	0x4f, 0x4e, 0x45, 0x4f, 0x46, 0x5f, 0x44, 0x49, 0x53, 0x43, 0x52, 0x49, 0x4d, 0x49, 0x4e, 0x41,
+	0x54, 0x4f, 0x52, 0x10, 0x03, 0x2a, 0x75, 0x0a, 0x11, 0x52, 0x65, 0x63, 0x75, 0x72, 0x73, 0x69,
+	0x6f, 0x6e, 0x46, 0x61, 0x6c, 0x6c, 0x62, 0x61, 0x63, 0x6b, 0x12, 0x22, 0x0a, 0x1e, 0x52, 0x45,
+	0x43, 0x55, 0x52, 0x53, 0x49, 0x4f, 0x4e, 0x5f, 0x46, 0x41, 0x4c, 0x4c, 0x42, 0x41, 0x43, 0x4b,
+	0x5f, 0x55, 0x4e, 0x53, 0x50, 0x45, 0x43, 0x49, 0x46, 0x49, 0x45, 0x44, 0x10, 0x00, 0x12, 0x12,
+	0x0a, 0x0e, 0x52, 0x45, 0x43, 0x55, 0x52, 0x53, 0x49, 0x4f, 0x4e, 0x5f, 0x4f, 0x4d, 0x49, 0x54,
+	0x10, 0x01, 0x12, 0x12, 0x0a, 0x0e, 0x52, 0x45, 0x43, 0x55, 0x52, 0x53, 0x49, 0x4f, 0x4e, 0x5f,
+	0x4a, 0x53, 0x4f, 0x4e, 0x10, 0x02, 0x12, 0x14, 0x0a, 0x10, 0x52, 0x45, 0x43, 0x55, 0x52, 0x53,
+	0x49, 0x4f, 0x4e, 0x5f, 0x53, 0x54, 0x52, 0x49, 0x4e, 0x47, 0x10, 0x03, 0x3a, 0x6c, 0x0a, 0x0d,
+	0x62, 0x69, 0x67, 0x71, 0x75, 0x65, 0x72, 0x79, 0x5f, 0x6f, 0x70, 0x74, 0x73, 0x12, 0x1f, 0x2e,
+	0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e,
+	0x4d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x4f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0xfd,
+	0x07, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x25, 0x2e, 0x67, 0x65, 0x6e, 0x5f, 0x62, 0x71, 0x5f, 0x73,
+	0x63, 0x68, 0x65, 0x6d, 0x61, 0x2e, 0x42, 0x69, 0x67, 0x51, 0x75, 0x65, 0x72, 0x79, 0x4d, 0x65,
+	0x73, 0x73, 0x61, 0x67, 0x65, 0x4f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x52, 0x0c, 0x62, 0x69,
+	0x67, 0x71, 0x75, 0x65, 0x72, 0x79, 0x4f, 0x70, 0x74, 0x73, 0x3a, 0x6f, 0x0a, 0x12, 0x62, 0x69,
+	0x67, 0x71, 0x75, 0x65, 0x72, 0x79, 0x5f, 0x66, 0x69, 0x6c, 0x65, 0x5f, 0x6f, 0x70, 0x74, 0x73,
+	0x12, 0x1c, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62,
+	0x75, 0x66, 0x2e, 0x46, 0x69, 0x6c, 0x65, 0x4f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0xfd,
+	0x07, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x22, 0x2e, 0x67, 0x65, 0x6e, 0x5f, 0x62, 0x71, 0x5f, 0x73,
+	0x63, 0x68, 0x65, 0x6d, 0x61, 0x2e, 0x42, 0x69, 0x67, 0x51, 0x75, 0x65, 0x72, 0x79, 0x46, 0x69,
+	0x6c, 0x65, 0x4f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x52, 0x10, 0x62, 0x69, 0x67, 0x71, 0x75,
+	0x65, 0x72, 0x79, 0x46, 0x69, 0x6c, 0x65, 0x4f, 0x70, 0x74, 0x73, 0x42, 0x3c, 0x5a, 0x3a, 0x67,
+	0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x47, 0x6f, 0x6f, 0x67, 0x6c, 0x65,
+	0x43, 0x6c, 0x6f, 0x75, 0x64, 0x50, 0x6c, 0x61, 0x74, 0x66, 0x6f, 0x72, 0x6d, 0x2f, 0x70, 0x72,
+	0x6f, 0x74, 0x6f, 0x63, 0x2d, 0x67, 0x65, 0x6e, 0x2d, 0x62, 0x71, 0x2d, 0x73, 0x63, 0x68, 0x65,
+	0x6d, 0x61, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x73, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x33,
 }
 
 var (
@@ -167,19 +674,35 @@ func file_bq_table_proto_rawDescGZIP() []byte {
 	return file_bq_table_proto_rawDescData
 }
 
-var file_bq_table_proto_msgTypes = make([]protoimpl.MessageInfo, 1)
+var file_bq_table_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
+var file_bq_table_proto_msgTypes = make([]protoimpl.MessageInfo, 5)
 var file_bq_table_proto_goTypes = []interface{}{
-	(*BigQueryMessageOptions)(nil),    // 0: gen_bq_schema.BigQueryMessageOptions
-	(*descriptor.MessageOptions)(nil), // 1: google.protobuf.MessageOptions
+	(OneofStrategy)(0),                // 0: gen_bq_schema.OneofStrategy
+	(RecursionFallback)(0),            // 1: gen_bq_schema.RecursionFallback
+	(*BigQueryMessageOptions)(nil),    // 2: gen_bq_schema.BigQueryMessageOptions
+	(*TimePartitioning)(nil),          // 3: gen_bq_schema.TimePartitioning
+	(*RangePartitioning)(nil),         // 4: gen_bq_schema.RangePartitioning
+	(*BigQueryFileOptions)(nil),       // 5: gen_bq_schema.BigQueryFileOptions
+	nil,                               // 6: gen_bq_schema.BigQueryMessageOptions.LabelsEntry
+	(*descriptor.MessageOptions)(nil), // 7: google.protobuf.MessageOptions
+	(*descriptor.FileOptions)(nil),    // 8: google.protobuf.FileOptions
 }
 var file_bq_table_proto_depIdxs = []int32{
-	1, // 0: gen_bq_schema.bigquery_opts:extendee -> google.protobuf.MessageOptions
-	0, // 1: gen_bq_schema.bigquery_opts:type_name -> gen_bq_schema.BigQueryMessageOptions
-	2, // [2:2] is the sub-list for method output_type
-	2, // [2:2] is the sub-list for method input_type
-	1, // [1:2] is the sub-list for extension type_name
-	0, // [0:1] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	0,  // 0: gen_bq_schema.BigQueryMessageOptions.oneof_strategy:type_name -> gen_bq_schema.OneofStrategy
+	1,  // 1: gen_bq_schema.BigQueryMessageOptions.recursion_fallback:type_name -> gen_bq_schema.RecursionFallback
+	3,  // 2: gen_bq_schema.BigQueryMessageOptions.time_partitioning:type_name -> gen_bq_schema.TimePartitioning
+	4,  // 3: gen_bq_schema.BigQueryMessageOptions.range_partitioning:type_name -> gen_bq_schema.RangePartitioning
+	6,  // 4: gen_bq_schema.BigQueryMessageOptions.labels:type_name -> gen_bq_schema.BigQueryMessageOptions.LabelsEntry
+	0,  // 5: gen_bq_schema.BigQueryFileOptions.oneof_strategy:type_name -> gen_bq_schema.OneofStrategy
+	7,  // 6: gen_bq_schema.bigquery_opts:extendee -> google.protobuf.MessageOptions
+	8,  // 7: gen_bq_schema.bigquery_file_opts:extendee -> google.protobuf.FileOptions
+	2,  // 8: gen_bq_schema.bigquery_opts:type_name -> gen_bq_schema.BigQueryMessageOptions
+	5,  // 9: gen_bq_schema.bigquery_file_opts:type_name -> gen_bq_schema.BigQueryFileOptions
+	10, // [10:10] is the sub-list for method output_type
+	10, // [10:10] is the sub-list for method input_type
+	8,  // [8:10] is the sub-list for extension type_name
+	6,  // [6:8] is the sub-list for extension extendee
+	0,  // [0:6] is the sub-list for field type_name
 }
 
 func init() { file_bq_table_proto_init() }
@@ -200,19 +723,56 @@ func file_bq_table_proto_init() {
 				return nil
 			}
 		}
+		file_bq_table_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*TimePartitioning); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_bq_table_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*RangePartitioning); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_bq_table_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*BigQueryFileOptions); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_bq_table_proto_rawDesc,
-			NumEnums:      0,
-			NumMessages:   1,
-			NumExtensions: 1,
+			NumEnums:      2,
+			NumMessages:   5,
+			NumExtensions: 2,
 			NumServices:   0,
 		},
 		GoTypes:           file_bq_table_proto_goTypes,
 		DependencyIndexes: file_bq_table_proto_depIdxs,
+		EnumInfos:         file_bq_table_proto_enumTypes,
 		MessageInfos:      file_bq_table_proto_msgTypes,
 		ExtensionInfos:    file_bq_table_proto_extTypes,
 	}.Build()